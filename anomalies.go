@@ -0,0 +1,368 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind of anomaly found in a series
+type AnomalyType int
+
+const (
+	// Daily amount far from the ones of the surrounding days
+	AnomalyOutlier AnomalyType = iota
+	// Negative daily amount of a field that can only grow, usually a recount
+	AnomalyNegativeDelta
+	// Sudden and lasting change of the daily amounts
+	AnomalyLevelShift
+	// Days missing before the flagged one
+	AnomalyMissingDays
+)
+
+// Returns the description of the anomaly type
+func (t AnomalyType) String() string {
+	switch t {
+	case AnomalyOutlier:
+		return "valore anomalo"
+	case AnomalyNegativeDelta:
+		return "variazione negativa"
+	case AnomalyLevelShift:
+		return "cambio di livello"
+	case AnomalyMissingDays:
+		return "giorni mancanti"
+	default:
+		return "sconosciuta"
+	}
+}
+
+// Parameters of the anomaly detection.
+// Outliers are the days whose robust z-score, computed against the median and the MAD of the Window days
+// before and after them once the weekly reporting pattern is removed, exceeds Threshold.
+// Level shifts compare the medians of the ShiftWindow days before and after each day, in units of their MAD,
+// with ShiftThreshold
+type AnomalyConfig struct {
+	Window         int
+	Threshold      float64
+	ShiftWindow    int
+	ShiftThreshold float64
+}
+
+// Anomaly found on a day of a field, Valore is the daily amount and Punteggio the score compared with the threshold.
+// Note holds the notes published for the same day and exactly the same area, which may explain the anomaly:
+// national anomalies get only the national notes, regional ones leave out the notes about their provinces
+type Anomaly struct {
+	Data                    time.Time
+	Campo                   string
+	Denominazione_regione   string
+	Denominazione_provincia string
+	Tipo                    AnomalyType
+	Valore                  float64
+	Punteggio               float64
+	Note                    []NoteData
+}
+
+// Returns the configuration used by default: a two weeks window for outliers with the usual 3.5 threshold
+// of the modified z-score, and shifts of at least 4 MADs between two weeks
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Window:         7,
+		Threshold:      3.5,
+		ShiftWindow:    7,
+		ShiftThreshold: 4,
+	}
+}
+
+// Finds the anomalies of a series of the given kind, sorted by date.
+// Cumulative and stock fields are checked on their daily changes, the other ones on their values
+func DetectAnomalies(dates *[]time.Time, values *[]float64, kind FieldKind, config AnomalyConfig) (*[]Anomaly, error) {
+	if len(*dates) != len(*values) {
+		return nil, fmt.Errorf("dates and values have different lengths")
+	}
+	if config.Window < 1 || config.ShiftWindow < 1 || config.Threshold <= 0 || config.ShiftThreshold <= 0 {
+		return nil, fmt.Errorf("wrong anomaly configuration passed")
+	}
+
+	daily := *values
+	if kind == FieldCumulative || kind == FieldStock {
+		daily = make([]float64, len(*values))
+		for i, v := range *values {
+			if i > 0 {
+				daily[i] = v - (*values)[i-1]
+			} else {
+				daily[i] = math.NaN()
+			}
+		}
+	}
+
+	adjusted := removeWeeklyPattern(dates, daily)
+	anomalies := make([]Anomaly, 0)
+	for i, v := range daily {
+		if i > 0 {
+			missing := int(math.Round((*dates)[i].Sub((*dates)[i-1]).Hours()/24)) - 1
+			if missing > 0 {
+				anomalies = append(anomalies, Anomaly{Data: (*dates)[i], Tipo: AnomalyMissingDays, Valore: v, Punteggio: float64(missing)})
+			}
+		}
+		if math.IsNaN(v) {
+			continue
+		}
+
+		if v < 0 && (kind == FieldCumulative || kind == FieldFlow) {
+			anomalies = append(anomalies, Anomaly{Data: (*dates)[i], Tipo: AnomalyNegativeDelta, Valore: v, Punteggio: v})
+		}
+
+		neighbours := make([]float64, 0, 2*config.Window)
+		for j := i - config.Window; j <= i+config.Window; j++ {
+			if j >= 0 && j < len(daily) && j != i && !math.IsNaN(adjusted[j]) {
+				neighbours = append(neighbours, adjusted[j])
+			}
+		}
+		if z := robustZScore(adjusted[i], neighbours); math.Abs(z) > config.Threshold {
+			anomalies = append(anomalies, Anomaly{Data: (*dates)[i], Tipo: AnomalyOutlier, Valore: v, Punteggio: z})
+		}
+	}
+
+	// only the strongest day of each run of shifted days is kept
+	shifts := levelShifts(daily, config)
+	for i, score := range shifts {
+		if math.Abs(score) <= config.ShiftThreshold {
+			continue
+		}
+		if (i > 0 && math.Abs(shifts[i-1]) > math.Abs(score)) || (i < len(shifts)-1 && math.Abs(shifts[i+1]) >= math.Abs(score)) {
+			continue
+		}
+		anomalies = append(anomalies, Anomaly{Data: (*dates)[i], Tipo: AnomalyLevelShift, Valore: daily[i], Punteggio: score})
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].Data.Before(anomalies[j].Data)
+	})
+
+	return &anomalies, nil
+}
+
+// Divides the values by the median ratio of their weekday to the centered weekly average,
+// so that the usual drop of the reports after the weekend is not mistaken for an anomaly
+func removeWeeklyPattern(dates *[]time.Time, values []float64) []float64 {
+	ratios := make(map[time.Weekday][]float64)
+	for i := 3; i+3 < len(values); i++ {
+		window := values[i-3 : i+4]
+		if containsNaN(window) {
+			continue
+		}
+		sum := 0.0
+		for _, v := range window {
+			sum += v
+		}
+		if sum <= 0 {
+			continue
+		}
+		weekday := (*dates)[i].Weekday()
+		ratios[weekday] = append(ratios[weekday], values[i]*7/sum)
+	}
+
+	adjusted := make([]float64, len(values))
+	for i, v := range values {
+		adjusted[i] = v
+		if weekdayRatios, ok := ratios[(*dates)[i].Weekday()]; ok {
+			if factor := median(weekdayRatios); factor > 0 {
+				adjusted[i] /= factor
+			}
+		}
+	}
+
+	return adjusted
+}
+
+// Returns the modified z-score of a value against the others.
+// When their MAD is zero the mean absolute deviation is used, and when that is zero too any different value is infinitely far
+func robustZScore(value float64, others []float64) float64 {
+	if len(others) < 3 {
+		return 0
+	}
+
+	center := median(others)
+	deviations := make([]float64, len(others))
+	meanDeviation := 0.0
+	for i, v := range others {
+		deviations[i] = math.Abs(v - center)
+		meanDeviation += deviations[i] / float64(len(others))
+	}
+	mad := median(deviations)
+	if mad > 0 {
+		return 0.6745 * (value - center) / mad
+	}
+	if meanDeviation > 0 {
+		return (value - center) / (1.253314 * meanDeviation)
+	}
+	if value == center {
+		return 0
+	}
+
+	return math.Copysign(math.Inf(1), value-center)
+}
+
+// Returns for each day the difference between the medians of the following and the previous days, in units of their MAD.
+// Days without full windows score zero
+func levelShifts(daily []float64, config AnomalyConfig) []float64 {
+	scores := make([]float64, len(daily))
+	for i := config.ShiftWindow + 1; i+config.ShiftWindow <= len(daily); i++ {
+		before := daily[i-config.ShiftWindow : i]
+		after := daily[i : i+config.ShiftWindow]
+		if containsNaN(before) || containsNaN(after) {
+			continue
+		}
+
+		beforeMedian := median(before)
+		afterMedian := median(after)
+		deviations := make([]float64, 0, 2*config.ShiftWindow)
+		for _, v := range before {
+			deviations = append(deviations, math.Abs(v-beforeMedian))
+		}
+		for _, v := range after {
+			deviations = append(deviations, math.Abs(v-afterMedian))
+		}
+		mad := median(deviations)
+		if mad == 0 {
+			continue
+		}
+
+		scores[i] = 0.6745 * (afterMedian - beforeMedian) / mad
+	}
+
+	return scores
+}
+
+// Returns the median of the values
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+// Checks whether any of the values is NaN
+func containsNaN(values []float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sets the field and the area of the anomalies and links them to the notes of the same day and of exactly the same area
+func linkAnomalies(anomalies *[]Anomaly, notes *[]NoteData, fieldName, regionName, provinceName string) {
+	for i := range *anomalies {
+		a := &(*anomalies)[i]
+		a.Campo = strings.ToLower(fieldName)
+		a.Denominazione_regione = regionName
+		a.Denominazione_provincia = provinceName
+		if notes == nil {
+			continue
+		}
+
+		day := a.Data.Format("2006-01-02")
+		for _, n := range *notes {
+			if dayOf(n.Data) != day || !noteMatchesArea(n, regionName, provinceName, false) {
+				continue
+			}
+			a.Note = append(a.Note, n)
+		}
+	}
+}
+
+// Finds the anomalies of the given national field, linked to the national notes of the same day when notes is not nil
+func NationAnomalies(data *[]NationData, fieldName string, notes *[]NoteData, config AnomalyConfig) (*[]Anomaly, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := NationSeries(data, fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies, err := DetectAnomalies(dates, values, kind, config)
+	if err != nil {
+		return nil, err
+	}
+	linkAnomalies(anomalies, notes, fieldName, "", "")
+
+	return anomalies, nil
+}
+
+// Finds the anomalies of the given regional field for the given region,
+// linked to the notes of the same day and region when notes is not nil
+func RegionAnomalies(data *[]RegionData, fieldName string, regionName string, notes *[]NoteData, config AnomalyConfig) (*[]Anomaly, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := RegionSeries(data, fieldName, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies, err := DetectAnomalies(dates, values, kind, config)
+	if err != nil {
+		return nil, err
+	}
+	linkAnomalies(anomalies, notes, fieldName, regionName, "")
+
+	return anomalies, nil
+}
+
+// Finds the anomalies of the given provincial field for the given province,
+// linked to the notes of the same day and province when notes is not nil
+func ProvinceAnomalies(data *[]ProvinceData, fieldName string, provinceName string, notes *[]NoteData, config AnomalyConfig) (*[]Anomaly, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := ProvinceSeries(data, fieldName, provinceName)
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies, err := DetectAnomalies(dates, values, kind, config)
+	if err != nil {
+		return nil, err
+	}
+	provinceIndexes := GetProvinceIndexesByName(data, provinceName)
+	first := (*data)[(*provinceIndexes)[0]]
+	linkAnomalies(anomalies, notes, fieldName, first.Denominazione_regione, first.Denominazione_provincia)
+
+	return anomalies, nil
+}
+
+// Finds the anomalies of the given regional field for every region, sorted by date
+func RegionsAnomalies(data *[]RegionData, fieldName string, notes *[]NoteData, config AnomalyConfig) (*[]Anomaly, error) {
+	if len(*data) < 21 {
+		return nil, fmt.Errorf("not enough regional data")
+	}
+
+	firstDay := (*data)[:21]
+	anomalies := make([]Anomaly, 0)
+	for _, regionName := range GetRegionsNamesList(&firstDay) {
+		regionAnomalies, err := RegionAnomalies(data, fieldName, regionName, notes, config)
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, *regionAnomalies...)
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].Data.Before(anomalies[j].Data)
+	})
+
+	return &anomalies, nil
+}
@@ -0,0 +1,203 @@
+package covidgraphs
+
+import (
+	"testing"
+	"time"
+)
+
+// Returns a daily amount around 100 with a small variation, so that the deviations are not zero
+func steadyAmount(i int) float64 {
+	return 100 + float64((i*3)%5)
+}
+
+// Returns the days of the anomalies of the given type
+func anomalyDays(anomalies *[]Anomaly, kind AnomalyType) []time.Time {
+	days := make([]time.Time, 0)
+	for _, a := range *anomalies {
+		if a.Tipo == kind {
+			days = append(days, a.Data)
+		}
+	}
+	return days
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	// fewer reports on Sundays and Mondays, the first day is a Monday
+	weekdays := []float64{0.5, 1.1, 1.1, 1.1, 1.1, 1.1, 0.7}
+
+	tests := []struct {
+		name   string
+		kind   FieldKind
+		values func(i int) float64
+		want   map[AnomalyType][]int
+	}{
+		{"single outlier", FieldFlow, func(i int) float64 {
+			if i == 24 {
+				return 500
+			}
+			return steadyAmount(i)
+		}, map[AnomalyType][]int{AnomalyOutlier: {24}}},
+		{"single outlier on weekly reports", FieldFlow, func(i int) float64 {
+			if i == 24 {
+				return 500
+			}
+			return steadyAmount(i) * weekdays[i%7]
+		}, map[AnomalyType][]int{AnomalyOutlier: {24}}},
+		{"negative recount", FieldCumulative, func(i int) float64 {
+			total := 0.0
+			for day := 1; day <= i; day++ {
+				if day == 20 {
+					total -= 50
+				} else {
+					total += steadyAmount(day)
+				}
+			}
+			return total
+		}, map[AnomalyType][]int{AnomalyOutlier: {20}, AnomalyNegativeDelta: {20}}},
+		// stocks go down as well as up
+		{"stock going up and down", FieldStock, func(i int) float64 {
+			stock := 1000.0
+			for day := 1; day <= i; day++ {
+				stock += steadyAmount(day) - 102
+			}
+			return stock
+		}, map[AnomalyType][]int{}},
+		{"level shift", FieldFlow, func(i int) float64 {
+			if i >= 30 {
+				return 3 * steadyAmount(i)
+			}
+			return steadyAmount(i)
+		}, map[AnomalyType][]int{AnomalyLevelShift: {30}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days := testDays(60)
+			values := make([]float64, len(days))
+			for i := range values {
+				values[i] = test.values(i)
+			}
+			anomalies, err := DetectAnomalies(&days, &values, test.kind, DefaultAnomalyConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, kind := range []AnomalyType{AnomalyOutlier, AnomalyNegativeDelta, AnomalyLevelShift, AnomalyMissingDays} {
+				got := anomalyDays(anomalies, kind)
+				want := test.want[kind]
+				if len(got) != len(want) {
+					t.Fatalf("%v on %v, want on days %v", kind, got, want)
+				}
+				for i, day := range want {
+					if !got[i].Equal(testDay(day)) {
+						t.Fatalf("%v on %v, want on days %v", kind, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDetectAnomaliesNegativeDelta(t *testing.T) {
+	days := testDays(30)
+	values := make([]float64, len(days))
+	for i := range values {
+		values[i] = float64(100 * i)
+	}
+	values[15] = values[14] - 50
+	values[16] = values[14] + 100
+
+	anomalies, err := DetectAnomalies(&days, &values, FieldCumulative, DefaultAnomalyConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range *anomalies {
+		if a.Tipo == AnomalyNegativeDelta {
+			if !a.Data.Equal(testDay(15)) || a.Valore != -50 {
+				t.Errorf("negative delta of %v on %v, want -50 on %v", a.Valore, a.Data, testDay(15))
+			}
+			return
+		}
+	}
+	t.Error("negative delta not found")
+}
+
+func TestDetectAnomaliesMissingDays(t *testing.T) {
+	// two days are missing before the 20th one
+	days := testDays(40)
+	days = append(days[:18], days[20:]...)
+	values := make([]float64, len(days))
+	for i := range values {
+		values[i] = steadyAmount(i)
+	}
+
+	anomalies, err := DetectAnomalies(&days, &values, FieldFlow, DefaultAnomalyConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, a := range *anomalies {
+		if a.Tipo != AnomalyMissingDays {
+			continue
+		}
+		if found || !a.Data.Equal(testDay(20)) || a.Punteggio != 2 {
+			t.Errorf("%v missing days before %v, want 2 only before %v", a.Punteggio, a.Data, testDay(20))
+		}
+		found = true
+	}
+	if !found {
+		t.Error("missing days not found")
+	}
+}
+
+func TestDetectAnomaliesErrors(t *testing.T) {
+	days := testDays(3)
+	if _, err := DetectAnomalies(&days, &[]float64{1, 2}, FieldFlow, DefaultAnomalyConfig()); err == nil {
+		t.Error("expected an error for different lengths")
+	}
+	if _, err := DetectAnomalies(&days, &[]float64{1, 2, 3}, FieldFlow, AnomalyConfig{}); err == nil {
+		t.Error("expected an error for a wrong configuration")
+	}
+}
+
+func TestLinkAnomalies(t *testing.T) {
+	notes := []NoteData{
+		{Codice: "nazione", Data: "2020-03-03T17:00:00"},
+		{Codice: "regione", Data: "2020-03-03T17:00:00", Regione: "Emilia-Romagna"},
+		{Codice: "provincia", Data: "2020-03-03T17:00:00", Regione: "Emilia-Romagna", Provincia: "Forlì-Cesena"},
+		{Codice: "altra regione", Data: "2020-03-03T17:00:00", Regione: "Veneto"},
+		{Codice: "altro giorno", Data: "2020-03-04T17:00:00", Regione: "Emilia-Romagna"},
+	}
+
+	tests := []struct {
+		name         string
+		regionName   string
+		provinceName string
+		want         []string
+	}{
+		{"nation", "", "", []string{"nazione"}},
+		{"region", "Emilia Romagna", "", []string{"regione"}},
+		{"province", "Emilia-Romagna", "Forlì Cesena", []string{"provincia"}},
+		{"area without notes", "Lombardia", "", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			anomalies := []Anomaly{{Data: testDay(1), Tipo: AnomalyOutlier}}
+			linkAnomalies(&anomalies, &notes, "Nuovi_positivi", test.regionName, test.provinceName)
+
+			a := anomalies[0]
+			if a.Campo != "nuovi_positivi" || a.Denominazione_regione != test.regionName || a.Denominazione_provincia != test.provinceName {
+				t.Errorf("anomaly of %v in %v %v", a.Campo, a.Denominazione_regione, a.Denominazione_provincia)
+			}
+			if len(a.Note) != len(test.want) {
+				t.Fatalf("linked %v, want %v", a.Note, test.want)
+			}
+			for i, n := range a.Note {
+				if n.Codice != test.want[i] {
+					t.Fatalf("linked %v, want %v", a.Note, test.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package covidgraphs
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed data/posti_letto.csv
+var bedCapacityCSV string
+
+// Beds available in a region from the given date until the following revision, Fonte is the source of the figures
+type BedCapacity struct {
+	Data                    time.Time
+	Codice_regione          int
+	Denominazione_regione   string
+	Posti_terapia_intensiva int
+	Posti_area_medica       int
+	Fonte                   string
+}
+
+// Occupancy percentages of intensive care and ordinary ward beds on a day, NaN when the capacity is unknown
+type Occupancy struct {
+	Data              time.Time
+	Terapia_intensiva float64
+	Area_medica       float64
+}
+
+// Occupancy thresholds above which the pressure on hospitals was considered critical
+const (
+	SogliaTerapiaIntensiva = 30.0
+	SogliaAreaMedica       = 40.0
+)
+
+var bedCapacityMutex sync.Mutex
+var bedCapacityTable *[]BedCapacity
+
+// Returns the bed capacity table sorted by date, parsing the embedded data on first use
+// unless another table was loaded with LoadBedCapacity
+func GetBedCapacity() (*[]BedCapacity, error) {
+	bedCapacityMutex.Lock()
+	defer bedCapacityMutex.Unlock()
+
+	if bedCapacityTable == nil {
+		table, err := parseBedCapacity(strings.NewReader(bedCapacityCSV))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing embedded bed capacity data: %v", err)
+		}
+		bedCapacityTable = table
+	}
+
+	return bedCapacityTable, nil
+}
+
+// Replaces the bed capacity table, the embedded one by default, with the one read from the given CSV having the header
+//
+//	data,codice_regione,denominazione_regione,posti_terapia_intensiva,posti_area_medica,fonte
+//
+// and a row for each region and revision, valid from its date until the following revision of the region.
+// The source, like the Agenas or Ministero della Salute publication the figures come from, is required
+// and is shown on the plots. Lines starting with # are ignored
+func LoadBedCapacity(r io.Reader) error {
+	table, err := parseBedCapacity(r)
+	if err != nil {
+		return err
+	}
+
+	bedCapacityMutex.Lock()
+	bedCapacityTable = table
+	bedCapacityMutex.Unlock()
+	return nil
+}
+
+// Parses a bed capacity CSV
+func parseBedCapacity(r io.Reader) (*[]BedCapacity, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 6
+
+	table := make([]BedCapacity, 0)
+	header := true
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error while parsing bed capacity: %v", err)
+		}
+		if header {
+			header = false
+			continue
+		}
+
+		var row BedCapacity
+		row.Data, err = time.Parse("2006-01-02", line[0])
+		if err != nil {
+			return nil, fmt.Errorf("wrong date in bed capacity data: %v", err)
+		}
+		row.Codice_regione, err = strconv.Atoi(line[1])
+		if err != nil {
+			return nil, fmt.Errorf("wrong region code in bed capacity data: %v", err)
+		}
+		row.Denominazione_regione = line[2]
+		row.Posti_terapia_intensiva, err = strconv.Atoi(line[3])
+		if err != nil {
+			return nil, fmt.Errorf("wrong intensive care beds value: %v", err)
+		}
+		row.Posti_area_medica, err = strconv.Atoi(line[4])
+		if err != nil {
+			return nil, fmt.Errorf("wrong ordinary ward beds value: %v", err)
+		}
+		row.Fonte = strings.TrimSpace(line[5])
+		if row.Fonte == "" {
+			return nil, fmt.Errorf("missing source of bed capacity of %v on %v", row.Denominazione_regione, line[0])
+		}
+		table = append(table, row)
+	}
+
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].Data.Before(table[j].Data)
+	})
+
+	return &table, nil
+}
+
+// Returns the beds of the given region on the given day, from the latest revision not after it.
+// If there is no such revision the earliest one is used
+func GetRegionBedCapacity(regionName string, date time.Time) (*BedCapacity, error) {
+	table, err := GetBedCapacity()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *BedCapacity
+	for i, v := range *table {
+		if !sameAreaName(v.Denominazione_regione, regionName) {
+			continue
+		}
+		if found == nil || !v.Data.After(date) {
+			found = &(*table)[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no bed capacity for %v", regionName)
+	}
+
+	return found, nil
+}
+
+// Returns the national beds on the given day, summing the capacity of every region in the table
+func GetNationBedCapacity(date time.Time) (*BedCapacity, error) {
+	table, err := GetBedCapacity()
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make([]string, 0)
+	seen := make(map[int]bool)
+	for _, v := range *table {
+		if !seen[v.Codice_regione] {
+			seen[v.Codice_regione] = true
+			regions = append(regions, v.Denominazione_regione)
+		}
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no bed capacity available")
+	}
+
+	nation := BedCapacity{Denominazione_regione: "Italia"}
+	sources := make([]string, 0)
+	for _, regionName := range regions {
+		capacity, err := GetRegionBedCapacity(regionName, date)
+		if err != nil {
+			return nil, err
+		}
+		nation.Posti_terapia_intensiva += capacity.Posti_terapia_intensiva
+		nation.Posti_area_medica += capacity.Posti_area_medica
+		if capacity.Data.After(nation.Data) {
+			nation.Data = capacity.Data
+		}
+		found := false
+		for _, source := range sources {
+			found = found || source == capacity.Fonte
+		}
+		if !found {
+			sources = append(sources, capacity.Fonte)
+		}
+	}
+	nation.Fonte = strings.Join(sources, "; ")
+
+	return &nation, nil
+}
+
+// Returns the occupancy percentage of the beds, NaN when there are none
+func occupancy(patients float64, beds int) float64 {
+	if beds <= 0 {
+		return math.NaN()
+	}
+
+	return patients * 100 / float64(beds)
+}
+
+// Calculates the national occupancy of intensive care and ordinary ward beds for each day
+func NationOccupancy(data *[]NationData) (*[]Occupancy, error) {
+	dates, icu, err := NationSeries(data, "terapia_intensiva")
+	if err != nil {
+		return nil, err
+	}
+	_, ward, err := NationSeries(data, "ricoverati_con_sintomi")
+	if err != nil {
+		return nil, err
+	}
+
+	occupancies := make([]Occupancy, 0)
+	for i, date := range *dates {
+		capacity, err := GetNationBedCapacity(date)
+		if err != nil {
+			return nil, err
+		}
+		occupancies = append(occupancies, Occupancy{
+			Data:              date,
+			Terapia_intensiva: occupancy((*icu)[i], capacity.Posti_terapia_intensiva),
+			Area_medica:       occupancy((*ward)[i], capacity.Posti_area_medica),
+		})
+	}
+
+	return &occupancies, nil
+}
+
+// Calculates the occupancy of intensive care and ordinary ward beds of the given region for each day
+func RegionOccupancy(data *[]RegionData, regionName string) (*[]Occupancy, error) {
+	dates, icu, err := RegionSeries(data, "terapia_intensiva", regionName)
+	if err != nil {
+		return nil, err
+	}
+	_, ward, err := RegionSeries(data, "ricoverati_con_sintomi", regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	occupancies := make([]Occupancy, 0)
+	for i, date := range *dates {
+		capacity, err := GetRegionBedCapacity(regionName, date)
+		if err != nil {
+			return nil, err
+		}
+		occupancies = append(occupancies, Occupancy{
+			Data:              date,
+			Terapia_intensiva: occupancy((*icu)[i], capacity.Posti_terapia_intensiva),
+			Area_medica:       occupancy((*ward)[i], capacity.Posti_area_medica),
+		})
+	}
+
+	return &occupancies, nil
+}
@@ -0,0 +1,95 @@
+package covidgraphs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedBedCapacity(t *testing.T) {
+	capacity, err := GetRegionBedCapacity("Emilia Romagna", testDay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capacity.Denominazione_regione != "Emilia-Romagna" || capacity.Posti_terapia_intensiva <= 0 || capacity.Fonte == "" {
+		t.Errorf("bed capacity %+v", *capacity)
+	}
+
+	nation, err := GetNationBedCapacity(testDay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nation.Posti_terapia_intensiva < capacity.Posti_terapia_intensiva || nation.Fonte == "" {
+		t.Errorf("national bed capacity %+v", *nation)
+	}
+}
+
+func TestLoadBedCapacity(t *testing.T) {
+	defer func() {
+		// back to the embedded table
+		bedCapacityMutex.Lock()
+		bedCapacityTable = nil
+		bedCapacityMutex.Unlock()
+	}()
+
+	table := `data,codice_regione,denominazione_regione,posti_terapia_intensiva,posti_area_medica,fonte
+2020-03-05,3,Lombardia,900,8000,seconda revisione
+# righe commentate
+2020-03-01,3,Lombardia,800,7000,prima revisione
+2020-03-01,5,Veneto,500,4000,prima revisione
+`
+	if err := LoadBedCapacity(strings.NewReader(table)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		day    int
+		region string
+		icu    int
+		source string
+	}{
+		// before the first revision the earliest one is used
+		{0, "Lombardia", 800, "prima revisione"},
+		{2, "Lombardia", 800, "prima revisione"},
+		{3, "Lombardia", 900, "seconda revisione"},
+		{10, "Lombardia", 900, "seconda revisione"},
+		{10, "Veneto", 500, "prima revisione"},
+	}
+	for _, test := range tests {
+		capacity, err := GetRegionBedCapacity(test.region, testDay(test.day))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if capacity.Posti_terapia_intensiva != test.icu || capacity.Fonte != test.source {
+			t.Errorf("%v on %v has %d beds from %v, want %d from %v", test.region, testDay(test.day),
+				capacity.Posti_terapia_intensiva, capacity.Fonte, test.icu, test.source)
+		}
+	}
+
+	nation, err := GetNationBedCapacity(testDay(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nation.Posti_terapia_intensiva != 1400 || nation.Posti_area_medica != 12000 || nation.Fonte != "seconda revisione; prima revisione" {
+		t.Errorf("national bed capacity %+v", *nation)
+	}
+	if _, err := GetRegionBedCapacity("Lazio", testDay(3)); err == nil {
+		t.Error("expected an error for a region missing from the table")
+	}
+
+	// a wrong table leaves the loaded one as it is
+	if err := LoadBedCapacity(strings.NewReader("data,codice_regione,denominazione_regione,posti_terapia_intensiva,posti_area_medica,fonte\n2020-03-01,3,Lombardia,800,7000, \n")); err == nil {
+		t.Error("expected an error for a revision without source")
+	}
+	if capacity, err := GetRegionBedCapacity("Veneto", testDay(3)); err != nil || capacity.Posti_terapia_intensiva != 500 {
+		t.Errorf("table changed by a wrong one: %v %v", capacity, err)
+	}
+}
+
+func TestOccupazioneEmptyData(t *testing.T) {
+	if err, _ := OccupazioneNazione(&[]NationData{}, "", "", ChartOptions{}); err == nil {
+		t.Error("expected an error for empty national data")
+	}
+	if err, _ := OccupazioneRegione(&[]RegionData{}, "Lombardia", "", "", ChartOptions{}); err == nil {
+		t.Error("expected an error for empty regional data")
+	}
+}
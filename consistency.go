@@ -0,0 +1,214 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violation of an identity the upstream data should satisfy, Denominazione_regione is empty for national rows.
+// Valore is the value of the field on the left of the identity and Atteso the one computed from the right side
+type ConsistencyViolation struct {
+	Data                  string
+	Denominazione_regione string
+	Regola                string
+	Valore                int
+	Atteso                int
+	Differenza            int
+}
+
+// Result of a consistency check
+type ConsistencyReport struct {
+	RigheControllate int
+	Violazioni       []ConsistencyViolation
+}
+
+// Checks whether no violation was found
+func (report *ConsistencyReport) Ok() bool {
+	return len(report.Violazioni) == 0
+}
+
+// Returns an error describing the violations, nil when the data is consistent,
+// so that the check can be used as a gate before publishing plots
+func (report *ConsistencyReport) Err() error {
+	if report.Ok() {
+		return nil
+	}
+
+	first := report.Violazioni[0]
+	area := first.Denominazione_regione
+	if area == "" {
+		area = "Italia"
+	}
+	return fmt.Errorf("%d consistency violations found, the first on %v for %v: %v (%d instead of %d)",
+		len(report.Violazioni), first.Data, area, first.Regola, first.Valore, first.Atteso)
+}
+
+// Fields of a row the identities are defined on
+type consistencyRow struct {
+	data                  string
+	area                  string
+	ricoveratiConSintomi  int
+	terapiaIntensiva      int
+	totaleOspedalizzati   int
+	isolamentoDomiciliare int
+	totalePositivi        int
+	nuoviPositivi         int
+	dimessiGuariti        int
+	deceduti              int
+	totaleCasi            int
+	tamponi               int
+}
+
+// Returns the values of the fields compared between regional sums and the national row
+func (row consistencyRow) values() []int {
+	return []int{row.ricoveratiConSintomi, row.terapiaIntensiva, row.totaleOspedalizzati, row.isolamentoDomiciliare,
+		row.totalePositivi, row.nuoviPositivi, row.dimessiGuariti, row.deceduti, row.totaleCasi, row.tamponi}
+}
+
+// Names of the fields returned by values
+var consistencyFields = []string{"ricoverati_con_sintomi", "terapia_intensiva", "totale_ospedalizzati", "isolamento_domiciliare",
+	"totale_positivi", "nuovi_positivi", "dimessi_guariti", "deceduti", "totale_casi", "tamponi"}
+
+// Checks the identities within a row and, when previous is not nil, the ones with the row of the day before of the same area
+func (report *ConsistencyReport) checkRow(row consistencyRow, previous *consistencyRow) {
+	report.RigheControllate++
+	report.check(row, "totale_ospedalizzati = ricoverati_con_sintomi + terapia_intensiva",
+		row.totaleOspedalizzati, row.ricoveratiConSintomi+row.terapiaIntensiva)
+	report.check(row, "totale_positivi = totale_ospedalizzati + isolamento_domiciliare",
+		row.totalePositivi, row.totaleOspedalizzati+row.isolamentoDomiciliare)
+	report.check(row, "totale_casi = totale_positivi + dimessi_guariti + deceduti",
+		row.totaleCasi, row.totalePositivi+row.dimessiGuariti+row.deceduti)
+	if previous != nil {
+		report.check(row, "nuovi_positivi = variazione di totale_casi",
+			row.nuoviPositivi, row.totaleCasi-previous.totaleCasi)
+	}
+}
+
+// Adds a violation when the value differs from the expected one
+func (report *ConsistencyReport) check(row consistencyRow, rule string, value, expected int) {
+	if value == expected {
+		return
+	}
+
+	report.Violazioni = append(report.Violazioni, ConsistencyViolation{
+		Data:                  row.data,
+		Denominazione_regione: row.area,
+		Regola:                rule,
+		Valore:                value,
+		Atteso:                expected,
+		Differenza:            value - expected,
+	})
+}
+
+// Returns the fields of a national row
+func nationConsistencyRow(v NationData) consistencyRow {
+	return consistencyRow{
+		data:                  v.Data,
+		ricoveratiConSintomi:  v.Ricoverati_con_sintomi,
+		terapiaIntensiva:      v.Terapia_intensiva,
+		totaleOspedalizzati:   v.Totale_ospedalizzati,
+		isolamentoDomiciliare: v.Isolamento_domiciliare,
+		totalePositivi:        v.Totale_positivi,
+		nuoviPositivi:         v.Nuovi_positivi,
+		dimessiGuariti:        v.Dimessi_guariti,
+		deceduti:              v.Deceduti,
+		totaleCasi:            v.Totale_casi,
+		tamponi:               v.Tamponi,
+	}
+}
+
+// Returns the fields of a regional row
+func regionConsistencyRow(v RegionData) consistencyRow {
+	return consistencyRow{
+		data:                  v.Data,
+		area:                  v.Denominazione_regione,
+		ricoveratiConSintomi:  v.Ricoverati_con_sintomi,
+		terapiaIntensiva:      v.Terapia_intensiva,
+		totaleOspedalizzati:   v.Totale_ospedalizzati,
+		isolamentoDomiciliare: v.Isolamento_domiciliare,
+		totalePositivi:        v.Totale_positivi,
+		nuoviPositivi:         v.Nuovi_positivi,
+		dimessiGuariti:        v.Dimessi_guariti,
+		deceduti:              v.Deceduti,
+		totaleCasi:            v.Totale_casi,
+		tamponi:               v.Tamponi,
+	}
+}
+
+// Checks the identities of every national row
+func CheckNationConsistency(data *[]NationData) *ConsistencyReport {
+	report := &ConsistencyReport{Violazioni: make([]ConsistencyViolation, 0)}
+
+	var previous *consistencyRow
+	for _, v := range *data {
+		row := nationConsistencyRow(v)
+		report.checkRow(row, previous)
+		previous = &row
+	}
+
+	return report
+}
+
+// Checks the identities of every regional row
+func CheckRegionsConsistency(data *[]RegionData) *ConsistencyReport {
+	report := &ConsistencyReport{Violazioni: make([]ConsistencyViolation, 0)}
+
+	previous := make(map[string]consistencyRow)
+	for _, v := range *data {
+		row := regionConsistencyRow(v)
+		if previousRow, ok := previous[row.area]; ok {
+			report.checkRow(row, &previousRow)
+		} else {
+			report.checkRow(row, nil)
+		}
+		previous[row.area] = row
+	}
+
+	return report
+}
+
+// Checks the identities of every national and regional row and that, for each day,
+// the sums of the regional fields equal the national ones
+func CheckConsistency(nation *[]NationData, regions *[]RegionData) *ConsistencyReport {
+	report := CheckNationConsistency(nation)
+	regionsReport := CheckRegionsConsistency(regions)
+	report.Violazioni = append(report.Violazioni, regionsReport.Violazioni...)
+	report.RigheControllate += regionsReport.RigheControllate
+
+	sums := make(map[string][]int)
+	for _, v := range *regions {
+		day := dayOf(v.Data)
+		if _, ok := sums[day]; !ok {
+			sums[day] = make([]int, len(consistencyFields))
+		}
+		for i, value := range regionConsistencyRow(v).values() {
+			sums[day][i] += value
+		}
+	}
+
+	for _, v := range *nation {
+		row := nationConsistencyRow(v)
+		daySums, ok := sums[dayOf(v.Data)]
+		if !ok {
+			report.Violazioni = append(report.Violazioni, ConsistencyViolation{
+				Data:   v.Data,
+				Regola: "dati regionali presenti",
+			})
+			continue
+		}
+		for i, value := range row.values() {
+			report.check(row, fmt.Sprintf("%v = somma regionale di %v", consistencyFields[i], consistencyFields[i]), value, daySums[i])
+		}
+	}
+
+	return report
+}
+
+// Returns the day of a date string of the upstream data
+func dayOf(date string) string {
+	if i := strings.Index(date, "T"); i >= 0 {
+		return date[:i]
+	}
+
+	return date
+}
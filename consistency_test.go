@@ -0,0 +1,144 @@
+package covidgraphs
+
+import (
+	"testing"
+)
+
+// Returns a consistent national row of the given day
+func consistentNation(day string, ricoverati, terapia, isolamento, guariti, deceduti, nuovi int) NationData {
+	ospedalizzati := ricoverati + terapia
+	positivi := ospedalizzati + isolamento
+	return NationData{
+		Data:                   day + "T17:00:00",
+		Ricoverati_con_sintomi: ricoverati,
+		Terapia_intensiva:      terapia,
+		Totale_ospedalizzati:   ospedalizzati,
+		Isolamento_domiciliare: isolamento,
+		Totale_positivi:        positivi,
+		Nuovi_positivi:         nuovi,
+		Dimessi_guariti:        guariti,
+		Deceduti:               deceduti,
+		Totale_casi:            positivi + guariti + deceduti,
+		Tamponi:                1000,
+	}
+}
+
+// Returns a consistent regional row with the fields of the national one
+func consistentRegion(row NationData, regionName string) RegionData {
+	return RegionData{
+		Data:                   row.Data,
+		Denominazione_regione:  regionName,
+		Ricoverati_con_sintomi: row.Ricoverati_con_sintomi,
+		Terapia_intensiva:      row.Terapia_intensiva,
+		Totale_ospedalizzati:   row.Totale_ospedalizzati,
+		Isolamento_domiciliare: row.Isolamento_domiciliare,
+		Totale_positivi:        row.Totale_positivi,
+		Nuovi_positivi:         row.Nuovi_positivi,
+		Dimessi_guariti:        row.Dimessi_guariti,
+		Deceduti:               row.Deceduti,
+		Totale_casi:            row.Totale_casi,
+		Tamponi:                row.Tamponi,
+	}
+}
+
+func TestCheckNationConsistency(t *testing.T) {
+	// the second day adds 10 new cases to the 100 of the first one
+	tests := []struct {
+		name       string
+		change     func(row *NationData)
+		rule       string
+		difference int
+	}{
+		{"consistent", func(row *NationData) {}, "", 0},
+		{"hospitalised", func(row *NationData) { row.Ricoverati_con_sintomi -= 3 },
+			"totale_ospedalizzati = ricoverati_con_sintomi + terapia_intensiva", 3},
+		{"positives", func(row *NationData) { row.Isolamento_domiciliare -= 4 },
+			"totale_positivi = totale_ospedalizzati + isolamento_domiciliare", 4},
+		{"cases", func(row *NationData) { row.Deceduti += 2 },
+			"totale_casi = totale_positivi + dimessi_guariti + deceduti", -2},
+		{"new cases", func(row *NationData) { row.Nuovi_positivi = 12 },
+			"nuovi_positivi = variazione di totale_casi", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := []NationData{
+				consistentNation("2020-03-02", 20, 5, 50, 20, 5, 100),
+				consistentNation("2020-03-03", 22, 6, 55, 22, 5, 10),
+			}
+			test.change(&data[1])
+			report := CheckNationConsistency(&data)
+
+			if report.RigheControllate != 2 {
+				t.Errorf("%d rows checked, want 2", report.RigheControllate)
+			}
+			if test.rule == "" {
+				if !report.Ok() || report.Err() != nil {
+					t.Fatalf("violations found: %v", report.Violazioni)
+				}
+				return
+			}
+			if len(report.Violazioni) != 1 || report.Err() == nil {
+				t.Fatalf("violations %v, want only %v", report.Violazioni, test.rule)
+			}
+			v := report.Violazioni[0]
+			if v.Regola != test.rule || v.Differenza != test.difference || v.Valore-v.Atteso != v.Differenza || v.Data != data[1].Data {
+				t.Errorf("violation %+v, want %v by %d on %v", v, test.rule, test.difference, data[1].Data)
+			}
+		})
+	}
+}
+
+func TestCheckRegionsConsistency(t *testing.T) {
+	// each region is compared with its own day before, whatever the order of the rows
+	first := consistentNation("2020-03-02", 20, 5, 50, 20, 5, 100)
+	second := consistentNation("2020-03-03", 22, 6, 55, 22, 5, 10)
+	data := []RegionData{
+		consistentRegion(first, "Lombardia"), consistentRegion(first, "Veneto"),
+		consistentRegion(second, "Lombardia"), consistentRegion(second, "Veneto"),
+	}
+	data[3].Nuovi_positivi = 7
+
+	report := CheckRegionsConsistency(&data)
+	if report.RigheControllate != 4 || len(report.Violazioni) != 1 {
+		t.Fatalf("%d rows checked with violations %v, want 4 with one", report.RigheControllate, report.Violazioni)
+	}
+	if v := report.Violazioni[0]; v.Denominazione_regione != "Veneto" || v.Differenza != -3 {
+		t.Errorf("violation %+v, want one of Veneto by -3", v)
+	}
+}
+
+func TestCheckConsistencyRegionalSums(t *testing.T) {
+	day := consistentNation("2020-03-02", 20, 5, 50, 20, 5, 100)
+	lombardia := consistentNation("2020-03-02", 12, 3, 30, 10, 3, 60)
+	veneto := consistentNation("2020-03-02", 8, 2, 20, 10, 2, 40)
+	nation := []NationData{day, consistentNation("2020-03-03", 22, 6, 55, 22, 5, 10)}
+	regions := []RegionData{consistentRegion(lombardia, "Lombardia"), consistentRegion(veneto, "Veneto")}
+	regions[0].Tamponi, regions[1].Tamponi = 600, 400
+
+	// Veneto moves a patient from the ward to intensive care, the national row does not
+	regions[1].Ricoverati_con_sintomi--
+	regions[1].Terapia_intensiva++
+
+	report := CheckConsistency(&nation, &regions)
+	if report.RigheControllate != 4 {
+		t.Errorf("%d rows checked, want 4", report.RigheControllate)
+	}
+	want := map[string]int{
+		"ricoverati_con_sintomi = somma regionale di ricoverati_con_sintomi": 1,
+		"terapia_intensiva = somma regionale di terapia_intensiva":           -1,
+		"dati regionali presenti":                                            0,
+	}
+	if len(report.Violazioni) != len(want) {
+		t.Fatalf("violations %v, want %v", report.Violazioni, want)
+	}
+	for _, v := range report.Violazioni {
+		difference, ok := want[v.Regola]
+		if !ok || v.Differenza != difference {
+			t.Errorf("violation %+v not expected", v)
+		}
+		if v.Regola == "dati regionali presenti" && v.Data != nation[1].Data {
+			t.Errorf("regional data missing on %v, want %v", v.Data, nation[1].Data)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Smallest number of days two series must share to be correlated at a lag
+const minCorrelationDays = 14
+
+// Pearson correlation between the leading series and the following one shifted back by Ritardo days,
+// computed over Giorni days. It is NaN when there are not enough days or one of the series is constant
+type LagCorrelation struct {
+	Ritardo      int
+	Correlazione float64
+	Giorni       int
+}
+
+// Cross-correlation of two series over a range of lags, with the lag the following series trails the leading one by
+type CrossCorrelation struct {
+	Correlazioni        []LagCorrelation
+	MigliorRitardo      int
+	MigliorCorrelazione float64
+}
+
+// Correlates the leading series with the following one for each lag between minLag and maxLag days,
+// a positive lag meaning that the following series moves after the leading one.
+// The best lag is the one with the highest correlation
+func CrossCorrelate(leadDates *[]time.Time, leadValues *[]float64, followDates *[]time.Time, followValues *[]float64, minLag, maxLag int) (*CrossCorrelation, error) {
+	if len(*leadDates) != len(*leadValues) || len(*followDates) != len(*followValues) {
+		return nil, fmt.Errorf("dates and values have different lengths")
+	}
+	if minLag > maxLag {
+		return nil, fmt.Errorf("wrong lag range passed")
+	}
+
+	follow := make(map[string]float64)
+	for i, date := range *followDates {
+		follow[date.Format("2006-01-02")] = (*followValues)[i]
+	}
+
+	result := CrossCorrelation{Correlazioni: make([]LagCorrelation, 0), MigliorCorrelazione: math.NaN()}
+	for lag := minLag; lag <= maxLag; lag++ {
+		x := make([]float64, 0)
+		y := make([]float64, 0)
+		for i, date := range *leadDates {
+			value, ok := follow[date.AddDate(0, 0, lag).Format("2006-01-02")]
+			if !ok || math.IsNaN(value) || math.IsNaN((*leadValues)[i]) {
+				continue
+			}
+			x = append(x, (*leadValues)[i])
+			y = append(y, value)
+		}
+
+		correlation := math.NaN()
+		if len(x) >= minCorrelationDays {
+			correlation = pearson(x, y)
+		}
+		result.Correlazioni = append(result.Correlazioni, LagCorrelation{Ritardo: lag, Correlazione: correlation, Giorni: len(x)})
+		if !math.IsNaN(correlation) && (math.IsNaN(result.MigliorCorrelazione) || correlation > result.MigliorCorrelazione) {
+			result.MigliorRitardo = lag
+			result.MigliorCorrelazione = correlation
+		}
+	}
+	if math.IsNaN(result.MigliorCorrelazione) {
+		return nil, fmt.Errorf("not enough data to correlate the series")
+	}
+
+	return &result, nil
+}
+
+// Returns the Pearson correlation coefficient of two series of the same length, NaN when one of them is constant
+func pearson(x, y []float64) float64 {
+	meanX, meanY := 0.0, 0.0
+	for i := range x {
+		meanX += x[i] / float64(len(x))
+		meanY += y[i] / float64(len(y))
+	}
+
+	covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+	for i := range x {
+		covariance += (x[i] - meanX) * (y[i] - meanY)
+		varianceX += (x[i] - meanX) * (x[i] - meanX)
+		varianceY += (y[i] - meanY) * (y[i] - meanY)
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return math.NaN()
+	}
+
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+// Prepares a field series for correlation: cumulative fields are turned into daily amounts
+// and every series is smoothed with a centered weekly average to remove the reporting cycle.
+// Days whose average includes the first day of a cumulative field are NaN
+func correlationSeries(values *[]float64, kind FieldKind) (*[]float64, error) {
+	daily := *values
+	if kind == FieldCumulative {
+		daily = make([]float64, len(*values))
+		for i, v := range *values {
+			if i > 0 {
+				daily[i] = v - (*values)[i-1]
+			} else {
+				daily[i] = math.NaN()
+			}
+		}
+	}
+
+	return SimpleMovingAverage(&daily, 7, true)
+}
+
+// Returns the values of a field prepared for correlation
+func preparedSeries(values *[]float64, fieldName string) (*[]float64, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	return correlationSeries(values, kind)
+}
+
+// Correlates two national fields over the given lags, the following field trailing the leading one
+func NationCrossCorrelation(data *[]NationData, leadField, followField string, minLag, maxLag int) (*CrossCorrelation, error) {
+	leadDates, leadValues, err := NationSeries(data, leadField)
+	if err != nil {
+		return nil, err
+	}
+	followDates, followValues, err := NationSeries(data, followField)
+	if err != nil {
+		return nil, err
+	}
+
+	return crossCorrelateFields(leadDates, leadValues, leadField, followDates, followValues, followField, minLag, maxLag)
+}
+
+// Correlates two regional fields of the given region over the given lags, the following field trailing the leading one
+func RegionCrossCorrelation(data *[]RegionData, leadField, followField string, regionName string, minLag, maxLag int) (*CrossCorrelation, error) {
+	leadDates, leadValues, err := RegionSeries(data, leadField, regionName)
+	if err != nil {
+		return nil, err
+	}
+	followDates, followValues, err := RegionSeries(data, followField, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return crossCorrelateFields(leadDates, leadValues, leadField, followDates, followValues, followField, minLag, maxLag)
+}
+
+// Prepares the series of two fields and correlates them
+func crossCorrelateFields(leadDates *[]time.Time, leadValues *[]float64, leadField string, followDates *[]time.Time, followValues *[]float64, followField string, minLag, maxLag int) (*CrossCorrelation, error) {
+	leadValues, err := preparedSeries(leadValues, leadField)
+	if err != nil {
+		return nil, err
+	}
+	followValues, err = preparedSeries(followValues, followField)
+	if err != nil {
+		return nil, err
+	}
+
+	return CrossCorrelate(leadDates, leadValues, followDates, followValues, minLag, maxLag)
+}
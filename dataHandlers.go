@@ -31,6 +31,11 @@ type NationData struct {
 	Totale_casi            int    `json:"totale_casi"`
 	Tamponi                int    `json:"tamponi"`
 	Note_it                string `json:"note_it"`
+
+	Totale_positivi_test_molecolare        int `json:"totale_positivi_test_molecolare"`
+	Totale_positivi_test_antigenico_rapido int `json:"totale_positivi_test_antigenico_rapido"`
+	Tamponi_test_molecolare                int `json:"tamponi_test_molecolare"`
+	Tamponi_test_antigenico_rapido         int `json:"tamponi_test_antigenico_rapido"`
 }
 
 // Regional data struct containing fields from the parsed JSON
@@ -52,6 +57,11 @@ type RegionData struct {
 	Totale_casi            int     `json:"totale_casi"`
 	Tamponi                int     `json:"tamponi"`
 	Note_it                string  `json:"note_it"`
+
+	Totale_positivi_test_molecolare        int `json:"totale_positivi_test_molecolare"`
+	Totale_positivi_test_antigenico_rapido int `json:"totale_positivi_test_antigenico_rapido"`
+	Tamponi_test_molecolare                int `json:"tamponi_test_molecolare"`
+	Tamponi_test_antigenico_rapido         int `json:"tamponi_test_antigenico_rapido"`
 }
 
 // Provincial data struct containing fields from the parsed JSON
@@ -593,34 +603,41 @@ func GetSudRegionsNamesList() []string {
 	return sudRegions
 }
 
-// Returns top regions according to field totale_contagi
+// Returns top ten regions of the latest day according to field totale_contagi, see RankRegions for other rankings
 func GetTopTenRegionsTotaleContagi(data *[]RegionData) *[]RegionData {
 	latestData := make([]RegionData, 21)
 	copy(latestData, (*data)[len(*data)-21:len(*data)])
 
-	sort.Slice(latestData, func(i, j int) bool {
-		return latestData[i].Totale_casi > latestData[j].Totale_casi
+	sort.SliceStable(latestData, func(i, j int) bool {
+		if latestData[i].Totale_casi != latestData[j].Totale_casi {
+			return latestData[i].Totale_casi > latestData[j].Totale_casi
+		}
+		return latestData[i].Denominazione_regione < latestData[j].Denominazione_regione
 	})
+	latestData = latestData[:10]
 
 	return &latestData
 }
 
-// Returns top provinces according to field totale_casi
+// Returns top ten provinces of the latest day according to field totale_casi, see RankProvinces for other rankings
 func GetTopTenProvincesTotaleContagi(data *[]ProvinceData) *[]ProvinceData {
-	latestData:=make([]ProvinceData, 0)
-	latestDate, _:=time.Parse("2006-01-02T15:04:05", (*data)[len(*data)-1].Data)
-	for i:=len(*data)-1; i>0; i--{
-		date, _:=time.Parse("2006-01-02T15:04:05", (*data)[i].Data)
-		if latestDate.Day()-date.Day()>0{
-			break
-		}else{
-			latestData=append(latestData, (*data)[i])
+	latestData := make([]ProvinceData, 0)
+	for i := firstLatestProvinceIndex(data); i < len(*data); i++ {
+		// rows of cases not yet assigned to a province have no sigla
+		if (*data)[i].Sigla_provincia != "" {
+			latestData = append(latestData, (*data)[i])
 		}
 	}
 
-	sort.Slice(latestData, func(i, j int) bool {
-		return latestData[i].Totale_casi > latestData[j].Totale_casi
+	sort.SliceStable(latestData, func(i, j int) bool {
+		if latestData[i].Totale_casi != latestData[j].Totale_casi {
+			return latestData[i].Totale_casi > latestData[j].Totale_casi
+		}
+		return latestData[i].Denominazione_provincia < latestData[j].Denominazione_provincia
 	})
+	if len(latestData) > 10 {
+		latestData = latestData[:10]
+	}
 
 	return &latestData
 }
@@ -755,17 +772,7 @@ func FindLastOccurrenceProvince(data *[]ProvinceData, fieldName string, toFind i
 func GetLastProvincesByRegionName(data *[]ProvinceData, regionName string) *[]ProvinceData {
 	provinces:=make([]ProvinceData, 0)
 
-	latestDate, _:=time.Parse("2006-01-02T15:04:05", (*data)[len(*data)-1].Data)
-	var firstLatestIndex int
-	for i:=len(*data)-1; i>0; i--{
-		date, _:=time.Parse("2006-01-02T15:04:05", (*data)[i].Data)
-		if latestDate.Day()-date.Day()>0{
-			firstLatestIndex=i+1
-			break
-		}
-	}
-
-	for i := firstLatestIndex; i<len(*data); i++ {
+	for i := firstLatestProvinceIndex(data); i<len(*data); i++ {
 		if strings.ToLower((*data)[i].Denominazione_regione) == strings.ToLower(regionName) &&
 			strings.ToLower((*data)[i].Denominazione_provincia) != "in fase di definizione/aggiornamento" &&
 			strings.ToLower((*data)[i].Denominazione_provincia) != "fuori regione / provincia autonoma" {
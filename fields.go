@@ -0,0 +1,55 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How the values of a data field evolve from one day to the next
+type FieldKind int
+
+const (
+	// Level observed on the day, like the people in intensive care
+	FieldStock FieldKind = iota
+	// Amount counted on the day, like the new positives
+	FieldFlow
+	// Running total since the beginning, like the total cases
+	FieldCumulative
+	// Percentage observed on the day, like the positivity rate
+	FieldRate
+)
+
+// Returns the name of the field kind
+func (k FieldKind) String() string {
+	switch k {
+	case FieldStock:
+		return "stock"
+	case FieldFlow:
+		return "flusso"
+	case FieldCumulative:
+		return "cumulativo"
+	case FieldRate:
+		return "tasso"
+	default:
+		return "sconosciuto"
+	}
+}
+
+// Returns the kind of the given field as accepted by the series builders
+func GetFieldKind(fieldName string) (FieldKind, error) {
+	switch strings.ToLower(fieldName) {
+	case "ricoverati_con_sintomi", "terapia_intensiva", "totale_ospedalizzati", "isolamento_domiciliare", "attualmente_positivi":
+		return FieldStock, nil
+	case "nuovi_positivi", "nuovi_tamponi", "nuovi_deceduti":
+		return FieldFlow, nil
+	case "dimessi_guariti", "deceduti", "totale_casi", "tamponi":
+		return FieldCumulative, nil
+	case "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico":
+		return FieldRate, nil
+	default:
+		if isRatioField(fieldName) {
+			return FieldRate, nil
+		}
+		return 0, fmt.Errorf("wrong field name passed")
+	}
+}
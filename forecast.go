@@ -0,0 +1,435 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Model used to forecast a series
+type ForecastMethod int
+
+const (
+	ForecastLogLinear ForecastMethod = iota
+	ForecastHoltWinters
+	ForecastARIMA
+)
+
+// Returns the name of the forecasting model
+func (m ForecastMethod) String() string {
+	switch m {
+	case ForecastLogLinear:
+		return "trend log-lineare"
+	case ForecastHoltWinters:
+		return "Holt-Winters"
+	case ForecastARIMA:
+		return "ARIMA"
+	default:
+		return "sconosciuto"
+	}
+}
+
+// Forecast parameters: Days is the projection horizon and PredictionInterval the probability covered by the interval.
+// The log-linear trend is fitted on the last Window days, Holt-Winters uses Alpha, Beta and Gamma as smoothing factors
+// of level, trend and weekly seasonality and ARIMA fits an autoregressive model of order P on the series differenced D times
+type ForecastConfig struct {
+	Method             ForecastMethod
+	Days               int
+	PredictionInterval float64
+	Window             int
+	Alpha              float64
+	Beta               float64
+	Gamma              float64
+	P                  int
+	D                  int
+}
+
+// Projected value of a day with its prediction interval
+type Forecast struct {
+	Data       time.Time
+	Previsione float64
+	Inferiore  float64
+	Superiore  float64
+}
+
+// Returns a two weeks log-linear forecast with a 95% prediction interval, fitted on the last four weeks.
+// The parameters of the other models are set to sensible values too, so that only Method needs to be changed
+func DefaultForecastConfig() ForecastConfig {
+	return ForecastConfig{
+		Method:             ForecastLogLinear,
+		Days:               14,
+		PredictionInterval: 0.95,
+		Window:             28,
+		Alpha:              0.3,
+		Beta:               0.05,
+		Gamma:              0.3,
+		P:                  2,
+		D:                  1,
+	}
+}
+
+// Forecasts the days following the series, which must be daily.
+// The log-linear trend needs values that are not negative and both it and Holt-Winters never project negative values
+func ForecastSeries(dates *[]time.Time, values *[]float64, config ForecastConfig) (*[]Forecast, error) {
+	if len(*dates) != len(*values) {
+		return nil, fmt.Errorf("dates and values have different lengths")
+	}
+	if len(*dates) == 0 {
+		return nil, fmt.Errorf("empty series passed")
+	}
+	if config.Days < 1 {
+		return nil, fmt.Errorf("wrong number of days passed")
+	}
+	if config.PredictionInterval <= 0 || config.PredictionInterval >= 1 {
+		return nil, fmt.Errorf("wrong prediction interval passed")
+	}
+
+	var predictions, errors []float64
+	var err error
+	switch config.Method {
+	case ForecastLogLinear:
+		predictions, errors, err = logLinearForecast(*values, config)
+	case ForecastHoltWinters:
+		predictions, errors, err = holtWintersForecast(*values, config)
+	case ForecastARIMA:
+		predictions, errors, err = arimaForecast(*values, config)
+	default:
+		return nil, fmt.Errorf("wrong forecast method passed")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	z := math.Sqrt2 * math.Erfinv(config.PredictionInterval)
+	last := (*dates)[len(*dates)-1]
+	forecasts := make([]Forecast, config.Days)
+	for h := range forecasts {
+		forecasts[h] = Forecast{
+			Data:       last.AddDate(0, 0, h+1),
+			Previsione: predictions[h],
+			Inferiore:  predictions[h] - z*errors[h],
+			Superiore:  predictions[h] + z*errors[h],
+		}
+
+		if config.Method == ForecastLogLinear {
+			// the interval is symmetric on the log scale
+			logPrediction := math.Log1p(predictions[h])
+			forecasts[h].Inferiore = math.Expm1(logPrediction - z*errors[h])
+			forecasts[h].Superiore = math.Expm1(logPrediction + z*errors[h])
+		}
+		if config.Method != ForecastARIMA {
+			forecasts[h].Previsione = math.Max(forecasts[h].Previsione, 0)
+			forecasts[h].Inferiore = math.Max(forecasts[h].Inferiore, 0)
+		}
+	}
+
+	return &forecasts, nil
+}
+
+// Fits a line to log(1+y) over the last days, returning the projections and the standard errors on the log scale
+func logLinearForecast(values []float64, config ForecastConfig) ([]float64, []float64, error) {
+	window := config.Window
+	if window < 3 {
+		return nil, nil, fmt.Errorf("wrong window passed")
+	}
+	if window > len(values) {
+		window = len(values)
+	}
+	if window < 3 {
+		return nil, nil, fmt.Errorf("not enough data")
+	}
+
+	fitted := values[len(values)-window:]
+	var sumX, sumY float64
+	for i, v := range fitted {
+		if v < 0 {
+			return nil, nil, fmt.Errorf("log-linear trend needs values that are not negative")
+		}
+		sumX += float64(i)
+		sumY += math.Log1p(v)
+	}
+	n := float64(window)
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy float64
+	for i, v := range fitted {
+		sxx += (float64(i) - meanX) * (float64(i) - meanX)
+		sxy += (float64(i) - meanX) * (math.Log1p(v) - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for i, v := range fitted {
+		residual := math.Log1p(v) - intercept - slope*float64(i)
+		sse += residual * residual
+	}
+	s := math.Sqrt(sse / (n - 2))
+
+	predictions := make([]float64, config.Days)
+	errors := make([]float64, config.Days)
+	for h := range predictions {
+		x := float64(window + h)
+		predictions[h] = math.Expm1(intercept + slope*x)
+		errors[h] = s * math.Sqrt(1+1/n+(x-meanX)*(x-meanX)/sxx)
+	}
+
+	return predictions, errors, nil
+}
+
+// Fits an additive Holt-Winters model with weekly seasonality, returning the projections and their standard errors
+func holtWintersForecast(values []float64, config ForecastConfig) ([]float64, []float64, error) {
+	const period = 7
+	for _, v := range []float64{config.Alpha, config.Beta, config.Gamma} {
+		if v < 0 || v > 1 {
+			return nil, nil, fmt.Errorf("wrong smoothing factors passed")
+		}
+	}
+	if len(values) < 2*period {
+		return nil, nil, fmt.Errorf("not enough data")
+	}
+
+	// trend starts from the means of the first two weeks, which are the levels of their middle days,
+	// and level and seasonality from the line through them at the end and on each day of the first week
+	var firstWeek, secondWeek float64
+	for i := 0; i < period; i++ {
+		firstWeek += values[i] / period
+		secondWeek += values[i+period] / period
+	}
+	trend := (secondWeek - firstWeek) / period
+	middle := float64(period-1) / 2
+	level := firstWeek + (float64(period-1)-middle)*trend
+	seasonal := make([]float64, period)
+	for i := range seasonal {
+		seasonal[i] = values[i] - (firstWeek + (float64(i)-middle)*trend)
+	}
+
+	var sse float64
+	steps := 0
+	for i := period; i < len(values); i++ {
+		season := seasonal[i%period]
+		residual := values[i] - (level + trend + season)
+		sse += residual * residual
+		steps++
+
+		previousLevel := level
+		level = config.Alpha*(values[i]-season) + (1-config.Alpha)*(level+trend)
+		trend = config.Beta*(level-previousLevel) + (1-config.Beta)*trend
+		seasonal[i%period] = config.Gamma*(values[i]-level) + (1-config.Gamma)*season
+	}
+	sigma := math.Sqrt(sse / float64(steps))
+
+	predictions := make([]float64, config.Days)
+	errors := make([]float64, config.Days)
+	variance := 1.0
+	for h := range predictions {
+		predictions[h] = level + float64(h+1)*trend + seasonal[(len(values)+h)%period]
+		errors[h] = sigma * math.Sqrt(variance)
+
+		// weight of the error of the step that will be added to the next horizon
+		j := h + 1
+		c := config.Alpha * (1 + float64(j)*config.Beta)
+		if j%period == 0 {
+			c += config.Gamma
+		}
+		variance += c * c
+	}
+
+	return predictions, errors, nil
+}
+
+// Fits an ARIMA(P, D, 0) model with a constant by least squares, returning the projections and their standard errors
+func arimaForecast(values []float64, config ForecastConfig) ([]float64, []float64, error) {
+	if config.P < 0 || config.D < 0 {
+		return nil, nil, fmt.Errorf("wrong ARIMA orders passed")
+	}
+
+	differenced := append([]float64(nil), values...)
+	for d := 0; d < config.D; d++ {
+		if len(differenced) < 2 {
+			return nil, nil, fmt.Errorf("not enough data")
+		}
+		next := make([]float64, len(differenced)-1)
+		for i := range next {
+			next[i] = differenced[i+1] - differenced[i]
+		}
+		differenced = next
+	}
+	p := config.P
+	observations := len(differenced) - p
+	if observations < p+2 {
+		return nil, nil, fmt.Errorf("not enough data")
+	}
+
+	// normal equations of the regression on the constant and the previous p values
+	normal := make([][]float64, p+1)
+	for i := range normal {
+		normal[i] = make([]float64, p+1)
+	}
+	rhs := make([]float64, p+1)
+	regressors := func(t int) []float64 {
+		x := make([]float64, p+1)
+		x[0] = 1
+		for k := 1; k <= p; k++ {
+			x[k] = differenced[t-k]
+		}
+		return x
+	}
+	for t := p; t < len(differenced); t++ {
+		x := regressors(t)
+		for i := range x {
+			for j := range x {
+				normal[i][j] += x[i] * x[j]
+			}
+			rhs[i] += x[i] * differenced[t]
+		}
+	}
+	coefficients, err := solveLinearSystem(normal, rhs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while fitting ARIMA: %v", err)
+	}
+
+	var sse float64
+	for t := p; t < len(differenced); t++ {
+		x := regressors(t)
+		fitted := 0.0
+		for i := range x {
+			fitted += coefficients[i] * x[i]
+		}
+		sse += (differenced[t] - fitted) * (differenced[t] - fitted)
+	}
+	sigma := math.Sqrt(sse / float64(observations-p-1))
+
+	// projections of the differenced series
+	extended := append([]float64(nil), differenced...)
+	for h := 0; h < config.Days; h++ {
+		next := coefficients[0]
+		for k := 1; k <= p; k++ {
+			next += coefficients[k] * extended[len(extended)-k]
+		}
+		extended = append(extended, next)
+	}
+	projected := extended[len(differenced):]
+
+	// integrates back the projections, each level of differencing starting from the last observed value
+	levels := make([][]float64, config.D+1)
+	levels[0] = values
+	for d := 1; d <= config.D; d++ {
+		levels[d] = make([]float64, len(levels[d-1])-1)
+		for i := range levels[d] {
+			levels[d][i] = levels[d-1][i+1] - levels[d-1][i]
+		}
+	}
+	for d := config.D - 1; d >= 0; d-- {
+		last := levels[d][len(levels[d])-1]
+		integrated := make([]float64, len(projected))
+		for h, v := range projected {
+			last += v
+			integrated[h] = last
+		}
+		projected = integrated
+	}
+
+	// psi weights of the autoregressive polynomial multiplied by the differencing one
+	polynomial := []float64{1}
+	for k := 1; k <= p; k++ {
+		polynomial = append(polynomial, -coefficients[k])
+	}
+	for d := 0; d < config.D; d++ {
+		next := make([]float64, len(polynomial)+1)
+		for i, v := range polynomial {
+			next[i] += v
+			next[i+1] -= v
+		}
+		polynomial = next
+	}
+	psi := make([]float64, config.Days)
+	for j := range psi {
+		if j == 0 {
+			psi[j] = 1
+			continue
+		}
+		for k := 1; k < len(polynomial) && k <= j; k++ {
+			psi[j] -= polynomial[k] * psi[j-k]
+		}
+	}
+
+	errors := make([]float64, config.Days)
+	variance := 0.0
+	for h := range errors {
+		variance += psi[h] * psi[h]
+		errors[h] = sigma * math.Sqrt(variance)
+	}
+
+	return projected, errors, nil
+}
+
+// Solves a linear system by Gaussian elimination with partial pivoting
+func solveLinearSystem(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = append(append([]float64(nil), a[i]...), b[i])
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, fmt.Errorf("singular system")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= factor * m[col][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+
+	return x, nil
+}
+
+// Forecasts the given national field
+func NationForecast(data *[]NationData, fieldName string, config ForecastConfig) (*[]Forecast, error) {
+	dates, values, err := NationSeries(data, fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ForecastSeries(dates, values, config)
+}
+
+// Forecasts the given regional field for the given region
+func RegionForecast(data *[]RegionData, fieldName string, regionName string, config ForecastConfig) (*[]Forecast, error) {
+	dates, values, err := RegionSeries(data, fieldName, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ForecastSeries(dates, values, config)
+}
+
+// Forecasts the given provincial field for the given province
+func ProvinceForecast(data *[]ProvinceData, fieldName string, provinceName string, config ForecastConfig) (*[]Forecast, error) {
+	dates, values, err := ProvinceSeries(data, fieldName, provinceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ForecastSeries(dates, values, config)
+}
@@ -0,0 +1,127 @@
+package covidgraphs
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestForecastExactSeries(t *testing.T) {
+	weekly := []float64{5, -3, 2, 0, -4, 1, -1}
+	config := DefaultForecastConfig()
+	config.Days = 10
+
+	tests := []struct {
+		name   string
+		method ForecastMethod
+		p, d   int
+		series func(i int) float64
+	}{
+		// log(1+y) is a line
+		{"log-linear exponential", ForecastLogLinear, 0, 0, func(i int) float64 { return 100*math.Pow(1.1, float64(i)) - 1 }},
+		{"Holt-Winters linear", ForecastHoltWinters, 0, 0, func(i int) float64 { return 50 + 2*float64(i) }},
+		{"Holt-Winters linear with weekly pattern", ForecastHoltWinters, 0, 0, func(i int) float64 { return 50 + 2*float64(i) + weekly[i%7] }},
+		{"ARIMA linear", ForecastARIMA, 0, 1, func(i int) float64 { return 5 + 3*float64(i) }},
+		// the differences follow d(t) = 1 + d(t-1)/2 from d(0) = 10
+		{"ARIMA autoregressive differences", ForecastARIMA, 1, 1, func(i int) float64 {
+			value, difference := 0.0, 10.0
+			for t := 1; t <= i; t++ {
+				value += difference
+				difference = 1 + difference/2
+			}
+			return value
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config.Method = test.method
+			config.P, config.D = test.p, test.d
+			values := make([]float64, 42)
+			for i := range values {
+				values[i] = test.series(i)
+			}
+			days := testDays(len(values))
+			forecasts, err := ForecastSeries(&days, &values, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(*forecasts) != config.Days {
+				t.Fatalf("got %d days, want %d", len(*forecasts), config.Days)
+			}
+			for h, v := range *forecasts {
+				want := test.series(len(values) + h)
+				if !v.Data.Equal(testDay(len(values) + h)) {
+					t.Errorf("day %d is %v, want %v", h, v.Data, testDay(len(values)+h))
+				}
+				if math.Abs(v.Previsione-want) > 1e-6*math.Max(1, math.Abs(want)) {
+					t.Errorf("day %d forecast %v, want %v", h, v.Previsione, want)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastIntervals(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	values := make([]float64, 60)
+	for i := range values {
+		values[i] = 200 + 5*float64(i) + 20*math.Sin(float64(i)*2*math.Pi/7) + 10*random.NormFloat64()
+	}
+	days := testDays(len(values))
+
+	for _, method := range []ForecastMethod{ForecastLogLinear, ForecastHoltWinters, ForecastARIMA} {
+		t.Run(method.String(), func(t *testing.T) {
+			config := DefaultForecastConfig()
+			config.Method = method
+			forecasts, err := ForecastSeries(&days, &values, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			previousWidth := 0.0
+			for h, v := range *forecasts {
+				if !(v.Inferiore < v.Previsione && v.Previsione < v.Superiore) {
+					t.Errorf("day %d forecast %v outside of its interval [%v, %v]", h, v.Previsione, v.Inferiore, v.Superiore)
+				}
+				width := v.Superiore - v.Inferiore
+				if width <= previousWidth {
+					t.Errorf("day %d interval width %v, not wider than the %v of the day before", h, width, previousWidth)
+				}
+				previousWidth = width
+			}
+		})
+	}
+}
+
+func TestForecastErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		config func(config *ForecastConfig)
+	}{
+		{"empty series", []float64{}, func(config *ForecastConfig) {}},
+		{"log-linear on two days", []float64{1, 2}, func(config *ForecastConfig) {}},
+		{"log-linear on negative values", []float64{1, -2, 3, 4}, func(config *ForecastConfig) {}},
+		{"Holt-Winters on less than two weeks", make([]float64, 13), func(config *ForecastConfig) { config.Method = ForecastHoltWinters }},
+		{"ARIMA on too few differences", []float64{1, 2, 4, 7}, func(config *ForecastConfig) { config.Method = ForecastARIMA }},
+		{"ARIMA with negative orders", make([]float64, 30), func(config *ForecastConfig) {
+			config.Method = ForecastARIMA
+			config.P = -1
+		}},
+		{"no days", make([]float64, 30), func(config *ForecastConfig) { config.Days = 0 }},
+		{"wrong prediction interval", make([]float64, 30), func(config *ForecastConfig) { config.PredictionInterval = 1 }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultForecastConfig()
+			test.config(&config)
+			days := testDays(len(test.values))
+			if _, err := ForecastSeries(&days, &test.values, config); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Growth indicators of a field on a day, rates are percentages and times are in days.
+// Daily fields are measured on their 7-day sums and cumulative fields on their 7-day increments, to remove
+// the weekly reporting cycle and to follow what is added rather than the running total, the other ones on their values.
+// VariazioneSettimanale is the difference from the measured value of a week before, in the unit of the field,
+// while CrescitaSettimanale is the same change as a percentage of that value.
+// Undefined values are NaN, as are the doubling time while decreasing and the halving time while increasing
+type GrowthRate struct {
+	Data                  time.Time
+	Valore                float64
+	CrescitaGiornaliera   float64
+	CrescitaSettimanale   float64
+	TempoRaddoppio        float64
+	TempoDimezzamento     float64
+	VariazioneSettimanale float64
+}
+
+// Calculates the growth indicators of a series of the given kind, starting from the first day having
+// the measured value of a week before. The daily growth rate is the average one over the last week.
+// The weekly growth rate and the week-over-week change compare the amounts of the last two weeks:
+// for cumulative fields the increments, for daily fields their sums and for the others the values
+func Growth(dates *[]time.Time, values *[]float64, kind FieldKind) (*[]GrowthRate, error) {
+	if len(*dates) != len(*values) {
+		return nil, fmt.Errorf("dates and values have different lengths")
+	}
+
+	measured := *values
+	first := 7
+	switch kind {
+	case FieldFlow:
+		measured = make([]float64, len(*values))
+		sum := 0.0
+		for i, v := range *values {
+			sum += v
+			if i >= 7 {
+				sum -= (*values)[i-7]
+			}
+			measured[i] = sum
+		}
+		first = 13
+	case FieldCumulative:
+		measured = make([]float64, len(*values))
+		for i := range *values {
+			if i >= 7 {
+				measured[i] = (*values)[i] - (*values)[i-7]
+			}
+		}
+		first = 14
+	}
+
+	growth := make([]GrowthRate, 0)
+	for i := first; i < len(measured); i++ {
+		g := GrowthRate{
+			Data:                  (*dates)[i],
+			Valore:                measured[i],
+			CrescitaGiornaliera:   math.NaN(),
+			CrescitaSettimanale:   percentChange(measured[i-7], measured[i]),
+			TempoRaddoppio:        math.NaN(),
+			TempoDimezzamento:     math.NaN(),
+			VariazioneSettimanale: measured[i] - measured[i-7],
+		}
+
+		if !math.IsNaN(g.CrescitaSettimanale) && g.CrescitaSettimanale > -100 {
+			ratio := 1 + g.CrescitaSettimanale/100
+			g.CrescitaGiornaliera = (math.Pow(ratio, 1.0/7) - 1) * 100
+			if ratio > 1 {
+				g.TempoRaddoppio = 7 * math.Ln2 / math.Log(ratio)
+			} else if ratio < 1 {
+				g.TempoDimezzamento = 7 * math.Ln2 / -math.Log(ratio)
+			}
+		}
+
+		growth = append(growth, g)
+	}
+
+	return &growth, nil
+}
+
+// Returns the percentage change from the previous value, undefined unless the previous one is positive
+// and the current one is not negative
+func percentChange(previous, current float64) float64 {
+	if previous <= 0 || current < 0 {
+		return math.NaN()
+	}
+
+	return (current/previous - 1) * 100
+}
+
+// Calculates the growth indicators of the given national field
+func NationGrowth(data *[]NationData, fieldName string) (*[]GrowthRate, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := NationSeries(data, fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Growth(dates, values, kind)
+}
+
+// Calculates the growth indicators of the given regional field for the given region
+func RegionGrowth(data *[]RegionData, fieldName string, regionName string) (*[]GrowthRate, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := RegionSeries(data, fieldName, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Growth(dates, values, kind)
+}
+
+// Calculates the growth indicators of the given provincial field for the given province
+func ProvinceGrowth(data *[]ProvinceData, fieldName string, provinceName string) (*[]GrowthRate, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := ProvinceSeries(data, fieldName, provinceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Growth(dates, values, kind)
+}
@@ -0,0 +1,110 @@
+package covidgraphs
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Returns the i-th day from Monday 2 March 2020
+func testDay(i int) time.Time {
+	return time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i)
+}
+
+// Returns the given number of consecutive days from Monday 2 March 2020
+func testDays(n int) []time.Time {
+	dates := make([]time.Time, n)
+	for i := range dates {
+		dates[i] = testDay(i)
+	}
+	return dates
+}
+
+// Checks whether two values are equal, NaN included
+func sameValue(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestGrowth(t *testing.T) {
+	constant := make([]float64, 28)
+	doubling := make([]float64, 28)
+	steadyTotal := make([]float64, 28)
+	slowingTotal := make([]float64, 28)
+	// nothing in the first week, then 10 a day
+	starting := make([]float64, 14)
+	for i := 7; i < len(starting); i++ {
+		starting[i] = 10
+	}
+	for i := range constant {
+		constant[i] = 10
+		doubling[i] = math.Pow(2, float64(i)/7)
+		steadyTotal[i] = float64(10 * (i + 1))
+		// 100 new cases a day for three weeks, then 50
+		if i == 0 {
+			slowingTotal[i] = 100
+		} else if i < 21 {
+			slowingTotal[i] = slowingTotal[i-1] + 100
+		} else {
+			slowingTotal[i] = slowingTotal[i-1] + 50
+		}
+	}
+
+	tests := []struct {
+		name     string
+		values   []float64
+		kind     FieldKind
+		first    int
+		valore   float64
+		weekly   float64
+		change   float64
+		doubling float64
+		halving  float64
+	}{
+		{"constant daily amounts", constant, FieldFlow, 13, 70, 0, 0, math.NaN(), math.NaN()},
+		{"daily amounts after a week without any", starting, FieldFlow, 13, 70, math.NaN(), 70, math.NaN(), math.NaN()},
+		{"stock doubling every week", doubling, FieldStock, 7, math.Pow(2, 27.0/7), 100, math.Pow(2, 20.0/7), 7, math.NaN()},
+		{"steady cumulative total", steadyTotal, FieldCumulative, 14, 70, 0, 0, math.NaN(), math.NaN()},
+		{"slowing cumulative total", slowingTotal, FieldCumulative, 14, 350, -50, -350, math.NaN(), 7},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days := testDays(len(test.values))
+			growth, err := Growth(&days, &test.values, test.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*growth) != len(test.values)-test.first || !(*growth)[0].Data.Equal(testDay(test.first)) {
+				t.Fatalf("got %d values from %v, want %d from %v", len(*growth), (*growth)[0].Data, len(test.values)-test.first, testDay(test.first))
+			}
+
+			last := (*growth)[len(*growth)-1]
+			if !sameValue(last.Valore, test.valore) {
+				t.Errorf("Valore %v, want %v", last.Valore, test.valore)
+			}
+			if !sameValue(last.CrescitaSettimanale, test.weekly) {
+				t.Errorf("CrescitaSettimanale %v, want %v", last.CrescitaSettimanale, test.weekly)
+			}
+			if !sameValue(last.VariazioneSettimanale, test.change) {
+				t.Errorf("VariazioneSettimanale %v, want %v", last.VariazioneSettimanale, test.change)
+			}
+			daily := (math.Pow(1+test.weekly/100, 1.0/7) - 1) * 100
+			if !sameValue(last.CrescitaGiornaliera, daily) {
+				t.Errorf("CrescitaGiornaliera %v, want %v", last.CrescitaGiornaliera, daily)
+			}
+			if !sameValue(last.TempoRaddoppio, test.doubling) || !sameValue(last.TempoDimezzamento, test.halving) {
+				t.Errorf("TempoRaddoppio %v and TempoDimezzamento %v, want %v and %v", last.TempoRaddoppio, last.TempoDimezzamento, test.doubling, test.halving)
+			}
+		})
+	}
+}
+
+func TestGrowthDifferentLengths(t *testing.T) {
+	days := testDays(3)
+	if _, err := Growth(&days, &[]float64{1, 2}, FieldStock); err == nil {
+		t.Fatal("expected an error for different lengths")
+	}
+}
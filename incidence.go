@@ -0,0 +1,84 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calculates the incidence per 100.000 inhabitants of the cases of the last days.
+// The first days-1 points are dropped since their window is incomplete
+func incidence(dates *[]time.Time, dailyCases *[]float64, population int, days int) (*[]time.Time, *[]float64) {
+	incidenceDates := make([]time.Time, 0)
+	values := make([]float64, 0)
+
+	sum := 0.0
+	for i, v := range *dailyCases {
+		sum += v
+		if i >= days {
+			sum -= (*dailyCases)[i-days]
+		}
+		if i >= days-1 {
+			incidenceDates = append(incidenceDates, (*dates)[i])
+			values = append(values, sum*100000/float64(population))
+		}
+	}
+
+	return &incidenceDates, &values
+}
+
+// Returns the national incidence of new cases per 100.000 inhabitants over the given number of days
+func NationIncidence(data *[]NationData, days int, year int) (*[]time.Time, *[]float64, error) {
+	if days < 1 {
+		return nil, nil, fmt.Errorf("wrong number of days passed")
+	}
+
+	dates, cases, err := NationSeries(data, "nuovi_positivi")
+	if err != nil {
+		return nil, nil, err
+	}
+	population, _, err := GetNationPopulation(year)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	incidenceDates, values := incidence(dates, cases, population, days)
+	return incidenceDates, values, nil
+}
+
+// Returns the incidence of new cases per 100.000 inhabitants over the given number of days for the given region
+func RegionIncidence(data *[]RegionData, regionName string, days int, year int) (*[]time.Time, *[]float64, error) {
+	if days < 1 {
+		return nil, nil, fmt.Errorf("wrong number of days passed")
+	}
+
+	dates, cases, err := RegionSeries(data, "nuovi_positivi", regionName)
+	if err != nil {
+		return nil, nil, err
+	}
+	population, _, err := GetRegionPopulation(regionName, year)
+	if err != nil {
+		return nil, nil, fmt.Errorf("region %v: %v", regionName, err)
+	}
+
+	incidenceDates, values := incidence(dates, cases, population, days)
+	return incidenceDates, values, nil
+}
+
+// Returns the incidence of new cases per 100.000 inhabitants over the given number of days for the given province
+func ProvinceIncidence(data *[]ProvinceData, provinceName string, days int, year int) (*[]time.Time, *[]float64, error) {
+	if days < 1 {
+		return nil, nil, fmt.Errorf("wrong number of days passed")
+	}
+
+	dates, cases, err := ProvinceSeries(data, "nuovi_positivi", provinceName)
+	if err != nil {
+		return nil, nil, err
+	}
+	population, _, err := GetProvincePopulation(provinceName, year)
+	if err != nil {
+		return nil, nil, fmt.Errorf("province %v: %v", provinceName, err)
+	}
+
+	incidenceDates, values := incidence(dates, cases, population, days)
+	return incidenceDates, values, nil
+}
@@ -0,0 +1,202 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/wcharczuk/go-chart"
+	"github.com/wcharczuk/go-chart/drawing"
+)
+
+// Default size of the plots in pixels
+const (
+	defaultWidth  = 1280
+	defaultHeight = 720
+)
+
+// Default font size of the ticks and of the legend
+const defaultFontSize = 15.0
+
+// Where the legend of a plot is drawn
+type LegendPosition int
+
+const (
+	// Inside the plot, in its top left corner, drawn by go-chart with its own colors
+	LegendDefault LegendPosition = iota
+	// Inside the plot, in its top left corner, with the colors of the theme
+	LegendTopLeft
+	// In columns below the plot, which grows to fit it
+	LegendBottom
+	// Not drawn
+	LegendHidden
+)
+
+// Loads a TrueType font to draw the texts of the plots with
+func LoadFont(path string) (*truetype.Font, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading font: %v", err)
+	}
+	font, err := truetype.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing font: %v", err)
+	}
+
+	return font, nil
+}
+
+// Returns the size of the image of the options
+func (o ChartOptions) size() (int, int) {
+	width, height := defaultWidth, defaultHeight
+	if o.Width > 0 {
+		width = o.Width
+	}
+	if o.Height > 0 {
+		height = o.Height
+	}
+
+	return width, height
+}
+
+// Returns the dots per inch of the options
+func (o ChartOptions) dpi() float64 {
+	if o.DPI > 0 {
+		return o.DPI
+	}
+	return chart.DefaultDPI
+}
+
+// Returns the font size of the ticks of the options
+func (o ChartOptions) fontSize() float64 {
+	if o.FontSize > 0 {
+		return o.FontSize
+	}
+	return defaultFontSize
+}
+
+// Returns the font size of the title of the options
+func (o ChartOptions) titleFontSize() float64 {
+	if o.TitleFontSize > 0 {
+		return o.TitleFontSize
+	}
+	return chart.DefaultTitleFontSize
+}
+
+// Returns the font size of the legend of the options. When none is given it is smaller with more than 10 entries,
+// unless the legend is the default one
+func (o ChartOptions) legendFontSize(entries int) float64 {
+	if o.LegendFontSize > 0 {
+		return o.LegendFontSize
+	}
+	if entries > 10 && o.Legend != LegendDefault {
+		return 9
+	}
+	return defaultFontSize
+}
+
+// Returns the padding of the plot, with the sides of the given one that are not zero replacing the computed ones.
+// The top is not reduced, as it leaves room for the title and the subtitle
+func (o ChartOptions) padding(computed chart.Box) chart.Box {
+	if o.Padding == nil {
+		return computed
+	}
+
+	padding := computed
+	if o.Padding.Top > computed.Top {
+		padding.Top = o.Padding.Top
+	}
+	if o.Padding.Left > 0 {
+		padding.Left = o.Padding.Left
+	}
+	if o.Padding.Right > 0 {
+		padding.Right = o.Padding.Right
+	}
+	if o.Padding.Bottom > 0 {
+		padding.Bottom = o.Padding.Bottom
+	}
+	return padding
+}
+
+// Entry of a legend
+type legendEntry struct {
+	name  string
+	style chart.Style
+}
+
+// Returns the entries of the legend of the series, the named ones that are neither hidden nor annotations
+func legendEntries(series []chart.Series) []legendEntry {
+	entries := make([]legendEntry, 0)
+	for _, s := range series {
+		if _, ok := s.(chart.AnnotationSeries); ok || s.GetName() == "" || s.GetStyle().Hidden {
+			continue
+		}
+		entries = append(entries, legendEntry{name: s.GetName(), style: s.GetStyle()})
+	}
+
+	return entries
+}
+
+// Width of a column of the legend below the plot
+const legendColumnWidth = 260
+
+// Returns the number of columns of the legend below a plot of the given width
+func bottomLegendColumns(width int) int {
+	return int(math.Max(1, float64((width-40)/legendColumnWidth)))
+}
+
+// Returns the height of a row of the legend below the plot
+func bottomLegendRowHeight(fontSize, dpi float64) int {
+	return int(math.Ceil(fontSize*dpi/72)) + 8
+}
+
+// Returns the height of the space taken by the legend below a plot of the given width
+func bottomLegendHeight(entries []legendEntry, width int, fontSize, dpi float64) int {
+	columns := bottomLegendColumns(width)
+	rows := (len(entries) + columns - 1) / columns
+	return 10 + rows*bottomLegendRowHeight(fontSize, dpi)
+}
+
+// Returns a renderable drawing the legend in columns below the plot, in the given space above the bottom of the image
+func bottomLegendRenderable(graph *chart.Chart, entries []legendEntry, bottom int, fontSize float64, fontColor drawing.Color) chart.Renderable {
+	return func(r chart.Renderer, canvasBox chart.Box, defaults chart.Style) {
+		columns := bottomLegendColumns(graph.GetWidth())
+		columnWidth := (graph.GetWidth() - 40) / columns
+		rowHeight := bottomLegendRowHeight(fontSize, graph.GetDPI())
+		top := graph.GetHeight() - bottom - bottomLegendHeight(entries, graph.GetWidth(), fontSize, graph.GetDPI()) + 10
+
+		for i, entry := range entries {
+			x := 20 + (i%columns)*columnWidth
+			y := top + (i/columns)*rowHeight + rowHeight/2
+
+			color := entry.style.StrokeColor
+			if color.IsZero() {
+				color = entry.style.FillColor
+			}
+			if color.IsZero() {
+				color = fontColor
+			}
+			r.SetStrokeColor(color)
+			r.SetStrokeWidth(math.Max(entry.style.StrokeWidth, 2))
+			r.SetStrokeDashArray(entry.style.StrokeDashArray)
+			r.MoveTo(x, y)
+			r.LineTo(x+25, y)
+			r.Stroke()
+			r.SetStrokeDashArray(nil)
+
+			// drawing lines does not reset the font, but the other renderables may have changed it
+			r.SetFont(defaults.GetFont())
+			r.SetFontSize(fontSize)
+			r.SetFontColor(fontColor)
+			text := entry.name
+			maxWidth := columnWidth - 40
+			for runes := []rune(entry.name); len(runes) > 0 && r.MeasureText(text).Width() > maxWidth; {
+				runes = runes[:len(runes)-1]
+				text = string(runes) + "..."
+			}
+			// the same baseline for every name, whatever letters it has
+			r.Text(text, x+30, y+(r.MeasureText("0").Height()>>1))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Criteria of a notes search, empty or zero fields match any note.
+// Regione and Provincia keep only the notes published for that area, Da and A bound the days of the notes
+// and Testo keeps the notes whose notice or additional notes contain all of its words.
+// Texts are compared ignoring case and accents
+type NoteQuery struct {
+	Regione          string
+	Provincia        string
+	Da               time.Time
+	A                time.Time
+	Tipologia_avviso string
+	Testo            string
+}
+
+// Returns all the notes matching the query, sorted by date. Notes with a wrong date are left out
+func SearchNotes(data *[]NoteData, query NoteQuery) (*[]NoteData, error) {
+	from := query.Da.Format("2006-01-02")
+	to := query.A.Format("2006-01-02")
+	if !query.Da.IsZero() && !query.A.IsZero() && from > to {
+		return nil, fmt.Errorf("wrong date range passed")
+	}
+	words := strings.Fields(foldText(query.Testo))
+
+	type datedNote struct {
+		date time.Time
+		note NoteData
+	}
+	matching := make([]datedNote, 0)
+	for _, n := range *data {
+		// a note with a wrong date cannot be placed in the range, but does not make the others unsearchable
+		date, err := noteDate(n)
+		if err != nil {
+			continue
+		}
+		day := date.Format("2006-01-02")
+		if (!query.Da.IsZero() && day < from) || (!query.A.IsZero() && day > to) {
+			continue
+		}
+		if query.Regione != "" && foldText(areaName(n.Regione)) != foldText(areaName(query.Regione)) {
+			continue
+		}
+		if query.Provincia != "" && foldText(areaName(n.Provincia)) != foldText(areaName(query.Provincia)) {
+			continue
+		}
+		if query.Tipologia_avviso != "" && foldText(n.Tipologia_avviso) != foldText(query.Tipologia_avviso) {
+			continue
+		}
+
+		text := foldText(n.Avviso + " " + n.Note)
+		found := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		matching = append(matching, datedNote{date: date, note: n})
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].date.Before(matching[j].date)
+	})
+	notes := make([]NoteData, len(matching))
+	for i, v := range matching {
+		notes[i] = v.note
+	}
+
+	return &notes, nil
+}
+
+// Returns an area name with hyphens replaced by spaces, as they are used inconsistently upstream
+func areaName(name string) string {
+	return strings.Replace(name, "-", " ", -1)
+}
+
+// Returns the text in lower case without accents and with single spaces
+func foldText(text string) string {
+	folded := []rune(strings.ToLower(strings.TrimSpace(text)))
+	for i, r := range folded {
+		if plain, ok := accents[r]; ok {
+			folded[i] = plain
+		}
+	}
+
+	return strings.Join(strings.Fields(string(folded)), " ")
+}
+
+// Letters with accents used in italian and in the other languages of the notes, with their plain versions
+var accents = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+	// typographic apostrophes are written in both ways
+	'’': '\'', '‘': '\'',
+}
+
+// Checks whether a note refers exactly to the given area: national notes to empty names, regional notes to the
+// region alone and provincial notes to the province. With provinceNotes the notes about the provinces of a region
+// refer to the region too
+func noteMatchesArea(note NoteData, regionName, provinceName string, provinceNotes bool) bool {
+	if !sameAreaName(note.Regione, regionName) {
+		return false
+	}
+	if regionName != "" && provinceName == "" && provinceNotes {
+		return true
+	}
+
+	return sameAreaName(note.Provincia, provinceName)
+}
+
+// Returns the day a note was published on
+func noteDate(note NoteData) (time.Time, error) {
+	return time.Parse("2006-01-02", dayOf(note.Data))
+}
+
+// Returns the text describing a note, its notice or the additional notes when the notice is empty
+func noteText(note NoteData) string {
+	text := strings.TrimSpace(note.Avviso)
+	if text == "" {
+		text = strings.TrimSpace(note.Note)
+	}
+
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,75 @@
+package covidgraphs
+
+import (
+	"io"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/wcharczuk/go-chart"
+)
+
+// Options accepted by every plot function, the zero value draws plots as usual
+type ChartOptions struct {
+	// Draws the values as bars with the line of the smoothed values on top
+	Smoothing *Smoothing
+	// Draws only the daily flow fields, like nuovi_positivi, nuovi_tamponi and nuovi_deceduti, as bars
+	// and leaves the other series as lines. With Smoothing the bars get the line of the smoothed values on top
+	// and the other series are drawn as their smoothed line
+	Bars bool
+	// Continues every series with a dashed forecast and its shaded prediction interval
+	Forecast *ForecastConfig
+	// Aggregates the values by ISO week or calendar month according to the kind of each field, along with the
+	// lines and bands drawn with them, leaving out daily annotations. It cannot be combined with Forecast
+	// nor used on plots shading days by label, like the zone and growth ones
+	Period Period
+	// Draws the values per 100.000 inhabitants of the area of the plot, leaving rates as they are, and ranks the
+	// areas by their values per 100.000 inhabitants. The population is the ISTAT one of PopulationYear,
+	// the latest available when not set
+	Per100k        bool
+	PopulationYear int
+	// Marks the days with notes about the area of the plot, numbered and listed below it
+	Notes *[]NoteData
+	// Marks on regional plots the notes about the provinces of the region too
+	ProvinceNotes bool
+	// Shades the waves of the first series in the background, labelled with their number and peak
+	Waves *WaveConfig
+	// Writes the image to the writer instead of a file, the plot functions then return an empty file name
+	Writer io.Writer
+	// Format of the image, PNG when not set
+	Format OutputFormat
+	// Quality of JPEG images, from 1 to 100, DefaultJPEGQuality when not set
+	JPEGQuality int
+	// Colors of the plot, like LightTheme, DarkTheme or HighContrastTheme
+	Theme *Theme
+	// Picks the light or the dark theme by the time of the day when no Theme is given,
+	// without either the light theme is used
+	AutoTheme *AutoTheme
+	// Size of the image in pixels, 1280x720 when not set. Notes and a legend below the plot make it taller
+	Width  int
+	Height int
+	// Dots per inch the font sizes are scaled by, the go-chart default when not set
+	DPI float64
+	// Font sizes of the ticks, of the title and of the legend, 15, chart.DefaultTitleFontSize and 15 when not set.
+	// Unless Legend is not set, the legend of plots with more than 10 series shrinks to 9 when its size is not set
+	FontSize       float64
+	TitleFontSize  float64
+	LegendFontSize float64
+	// Font of the texts, like one loaded with LoadFont, the go-chart default one when not set
+	Font *truetype.Font
+	// Where the legend is drawn, inside the top left corner of the plot in the go-chart style when not set
+	Legend LegendPosition
+	// Space around the plot, its sides that are not zero replacing the default ones. The top is never
+	// reduced below the room left for the title and the subtitle
+	Padding *chart.Box
+	// Names of the axes, replacing the ones of the plot when set
+	XAxisName string
+	YAxisName string
+}
+
+// Returns the options passed to a plot function or the default ones
+func getChartOptions(opts []ChartOptions) ChartOptions {
+	if len(opts) == 0 {
+		return ChartOptions{}
+	}
+
+	return opts[0]
+}
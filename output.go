@@ -0,0 +1,142 @@
+package covidgraphs
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/wcharczuk/go-chart"
+)
+
+// Image format of a plot
+type OutputFormat int
+
+const (
+	FormatPNG OutputFormat = iota
+	FormatSVG
+	FormatJPEG
+	FormatWebP
+)
+
+// Quality of JPEG images when none is given
+const DefaultJPEGQuality = 90
+
+// Returns the file extension of the format, dot included
+func (f OutputFormat) Extension() string {
+	switch f {
+	case FormatSVG:
+		return ".svg"
+	case FormatJPEG:
+		return ".jpg"
+	case FormatWebP:
+		return ".webp"
+	default:
+		return ".png"
+	}
+}
+
+// Returns the name of the format
+func (f OutputFormat) String() string {
+	switch f {
+	case FormatSVG:
+		return "svg"
+	case FormatJPEG:
+		return "jpeg"
+	case FormatWebP:
+		return "webp"
+	default:
+		return "png"
+	}
+}
+
+// Returns the go-chart renderer drawing the format. JPEG and WebP images are drawn as PNG and converted when saved
+func (f OutputFormat) renderer() chart.RendererProvider {
+	if f == FormatSVG {
+		return chart.SVG
+	}
+	return chart.PNG
+}
+
+// Wraps a writer so that the PNG written to it is converted to the format of the options
+func formatWriter(w io.Writer, options ChartOptions) (io.Writer, func() error) {
+	if options.Format != FormatJPEG && options.Format != FormatWebP {
+		return w, func() error { return nil }
+	}
+
+	var buffer bytes.Buffer
+	return &buffer, func() error {
+		img, err := png.Decode(&buffer)
+		if err != nil {
+			return err
+		}
+		if options.Format == FormatWebP {
+			return encodeWebP(w, img)
+		}
+		quality := options.JPEGQuality
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+		}
+		if quality < 1 || quality > 100 {
+			return fmt.Errorf("wrong jpeg quality passed")
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}
+}
+
+// Writes the image drawn by render, with the renderer of the format of the options, to the writer of the options or,
+// when there is none, to the given file, named after the title, the theme and the format when empty.
+// The file name is empty when writing to a writer
+func saveChart(render func(w io.Writer) error, options ChartOptions, title, filename, themeName string) (error, string) {
+	if options.Writer != nil {
+		err := renderFormat(render, options.Writer, options)
+		if err != nil {
+			return fmt.Errorf("error while rendering graph: %v", err), ""
+		}
+		return nil, ""
+	}
+
+	if filename == "" {
+		filename = title + "-" + themeName + options.Format.Extension()
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("error while creating file: %v", err), ""
+	}
+	defer f.Close()
+	err = renderFormat(render, f, options)
+	if err != nil {
+		return fmt.Errorf("error while rendering graph: %v", err), ""
+	}
+	return nil, filename
+}
+
+// Renders the image to the writer in the format of the options
+func renderFormat(render func(w io.Writer) error, w io.Writer, options ChartOptions) error {
+	target, convert := formatWriter(w, options)
+	err := render(target)
+	if err != nil {
+		return err
+	}
+	return convert()
+}
+
+// Returns the image of a plot drawn in memory. The plot function receives the options, with their Writer set,
+// to pass to one of the plot functions, like
+//
+//	PlotBytes(func(opts ChartOptions) (error, string) {
+//		return RtNazione(data, DefaultRtConfig(), "Rt", "", opts)
+//	})
+func PlotBytes(plot func(opts ChartOptions) (error, string), opts ...ChartOptions) ([]byte, error) {
+	var buffer bytes.Buffer
+	options := getChartOptions(opts)
+	options.Writer = &buffer
+
+	err, _ := plot(options)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,82 @@
+package covidgraphs
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/wcharczuk/go-chart"
+)
+
+func TestPlotBytesSingleImage(t *testing.T) {
+	nation := make([]NationData, 0)
+	provinces := make([]ProvinceData, 0)
+	provinceIndexes := make([]int, 0)
+	for i := 0; i < 30; i++ {
+		nation = append(nation, NationData{Data: upstreamDate(i), Nuovi_positivi: 100 + i*i%37*10, Terapia_intensiva: 50 + i, Totale_casi: 1000 + 100*i})
+		provinceIndexes = append(provinceIndexes, len(provinces))
+		provinces = append(provinces, ProvinceData{Data: upstreamDate(i), Denominazione_provincia: "Bologna", Sigla_provincia: "BO", Totale_casi: 100 + 10*i, NuoviCasi: 10})
+	}
+	regions := rankingRegions(map[string][]int{"Lombardia": {100, 150, 170}}, map[string][]int{"Lombardia": {30, 60, 90}})
+
+	tests := []struct {
+		name string
+		plot func(opts ChartOptions) (error, string)
+	}{
+		{"nation", func(opts ChartOptions) (error, string) {
+			return VociNazione(&nation, []string{"nuovi_positivi", "terapia_intensiva", "totale_casi"}, 0, "Italia", "", opts)
+		}},
+		{"region", func(opts ChartOptions) (error, string) {
+			return VociRegione(regions, []string{"totale_casi", "terapia_intensiva"}, 8, 8, "Lombardia", "", opts)
+		}},
+		{"province", func(opts ChartOptions) (error, string) {
+			return VociProvince(&provinces, []string{"totale_casi", "nuovi_positivi"}, &provinceIndexes, "Bologna", "", opts)
+		}},
+	}
+
+	// every field is drawn on the same plot, written once
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			png, err := PlotBytes(test.plot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count := bytes.Count(png, signature); count != 1 {
+				t.Fatalf("%d images written", count)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(png)); err != nil {
+				t.Fatalf("decoding png: %v", err)
+			}
+		})
+	}
+
+	if _, err := PlotBytes(func(opts ChartOptions) (error, string) {
+		return VociNazione(&nation, []string{}, 0, "Italia", "", opts)
+	}); err == nil {
+		t.Error("expected an error without fields")
+	}
+}
+
+func TestChartOptionsPadding(t *testing.T) {
+	computed := chart.Box{Top: 65, Bottom: 40}
+	tests := []struct {
+		name    string
+		padding *chart.Box
+		want    chart.Box
+	}{
+		{"not set", nil, computed},
+		{"sides only", &chart.Box{Left: 20, Right: 30}, chart.Box{Top: 65, Left: 20, Right: 30, Bottom: 40}},
+		{"smaller top", &chart.Box{Top: 10}, computed},
+		{"larger top and bottom", &chart.Box{Top: 80, Bottom: 60}, chart.Box{Top: 80, Bottom: 60}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ChartOptions{Padding: test.padding}.padding(computed)
+			if got != test.want {
+				t.Fatalf("padding %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
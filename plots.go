@@ -4,16 +4,14 @@ import (
 	"fmt"
 	"github.com/wcharczuk/go-chart"
 	"github.com/wcharczuk/go-chart/drawing"
-	"os"
+	"io"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-const (
-	daySwitch   = "06:00:00"
-	nightSwitch = "19:00:00"
-)
-
 // Calculates annotations containing the difference to the previous point on the plot
 func deltaAnnotations(deltas *[]string, xValues *[]time.Time, yValues *[]float64) chart.AnnotationSeries {
 	value2 := make([]chart.Value2, 0)
@@ -28,38 +26,210 @@ func deltaAnnotations(deltas *[]string, xValues *[]time.Time, yValues *[]float64
 	return chart.AnnotationSeries{Annotations: value2}
 }
 
+// Optional elements of a time series plot
+type plotExtras struct {
+	subtitle           string
+	background         []chart.Series
+	overlay            []chart.Series
+	secondaryYAxisName string
+	// kinds of the series used for resampling, series without one are looked up by name and averaged when unknown
+	kinds []FieldKind
+	// parts of the ratios among the series, by index, resampled as the ratio of their sums instead of by kind
+	ratios map[int]*ratioParts
+	// stacks the series on each other as filled areas, in percent of their sum when normalized
+	stacked    bool
+	normalized bool
+	plotArea
+}
+
+// Area a plot refers to, used to pick its notes, empty for national plots
+type plotArea struct {
+	regionName   string
+	provinceName string
+}
+
+// Returns the area of the region at the given index of the data
+func regionArea(data *[]RegionData, index int) plotArea {
+	if index < 0 || index >= len(*data) {
+		return plotArea{}
+	}
+
+	return plotArea{regionName: (*data)[index].Denominazione_regione}
+}
+
+// Returns the area of the province at the given indexes of the data
+func provinceArea(data *[]ProvinceData, provinceIndexes *[]int) plotArea {
+	if len(*provinceIndexes) == 0 || (*provinceIndexes)[0] >= len(*data) {
+		return plotArea{}
+	}
+	province := (*data)[(*provinceIndexes)[0]]
+
+	return plotArea{regionName: province.Denominazione_regione, provinceName: province.Denominazione_provincia}
+}
+
 // Creates a plot with the given series
-func timeseriesChart(charts *[]chart.TimeSeries, gridLines *[]chart.GridLine, annotations *[]chart.AnnotationSeries, title, filename, xAxisName, yAxisName string) (error, string) {
+func timeseriesChart(charts *[]chart.TimeSeries, gridLines *[]chart.GridLine, annotations *[]chart.AnnotationSeries, extras *plotExtras, title, filename, xAxisName, yAxisName string, opts ...ChartOptions) (error, string) {
+	if extras == nil {
+		extras = &plotExtras{}
+	}
+	options := getChartOptions(opts)
+	if extras.stacked {
+		if options.Forecast != nil {
+			return fmt.Errorf("error while stacking: forecasts are not available for stacked plots"), ""
+		}
+		if options.Smoothing != nil || options.Bars || options.Waves != nil {
+			return fmt.Errorf("error while stacking: smoothing, bars and waves are not available for stacked plots"), ""
+		}
+	}
+
+	if options.Per100k {
+		scaled, axes, populationYear, err := perCapitaCharts(charts, extras, options.PopulationYear)
+		if err != nil {
+			return fmt.Errorf("error while scaling to 100.000 inhabitants: %v", err), ""
+		}
+		if len(axes) > 0 {
+			charts = scaled
+			// the extras are shared with the caller, the scaled plot gets its own copy
+			scaledExtras := *extras
+			extras = &scaledExtras
+			if extras.subtitle != "" {
+				extras.subtitle += " - "
+			}
+			extras.subtitle += PopulationSource(populationYear)
+			if axes[chart.YAxisPrimary] {
+				yAxisName = perCapitaAxisName(yAxisName)
+			}
+			if axes[chart.YAxisSecondary] {
+				extras.secondaryYAxisName = perCapitaAxisName(extras.secondaryYAxisName)
+			}
+			// annotations hold the differences of the values before scaling
+			annotations = &[]chart.AnnotationSeries{}
+		}
+	}
+
+	// waves are found on the daily values, before any resampling
+	var waves *[]Wave
+	if options.Waves != nil && len(*charts) > 0 {
+		first := (*charts)[0]
+		var err error
+		waves, err = DetectWaves(&first.XValues, &first.YValues, seriesKind(0, first, extras.kinds), *options.Waves)
+		if err != nil {
+			return fmt.Errorf("error while detecting waves of %v: %v", first.Name, err), ""
+		}
+	}
+
+	var ticks []chart.Tick
+	background := extras.background
+	overlay := extras.overlay
+	if options.Period != PeriodDay {
+		if options.Forecast != nil {
+			return fmt.Errorf("error while resampling: forecasts need daily data"), ""
+		}
+		resampled, err := resampleCharts(charts, extras, options.Period)
+		if err != nil {
+			return fmt.Errorf("error while resampling: %v", err), ""
+		}
+		charts = resampled
+		background, err = resampleDecorations(extras.background, options.Period)
+		if err != nil {
+			return fmt.Errorf("error while resampling: %v", err), ""
+		}
+		overlay, err = resampleDecorations(extras.overlay, options.Period)
+		if err != nil {
+			return fmt.Errorf("error while resampling: %v", err), ""
+		}
+		gridLines, ticks = periodXAxis(charts, options.Period)
+		// daily annotations have no place in aggregated plots
+		annotations = &[]chart.AnnotationSeries{}
+	}
+	if waves != nil {
+		background = append(append(make([]chart.Series, 0), background...), wavesBackground(waves)...)
+	}
+	if extras.stacked {
+		stacked, err := stackCharts(charts, extras.normalized)
+		if err != nil {
+			return fmt.Errorf("error while stacking: %v", err), ""
+		}
+		charts = stacked
+	}
+
 	series := make([]chart.Series, 0)
-	for _, v := range *charts {
-		series = append(series, v)
+	series = append(series, background...)
+	for i, v := range *charts {
+		if extras.stacked {
+			series = append(series, stackedBand(charts, i))
+			continue
+		}
+		flow := seriesKind(i, v, extras.kinds) == FieldFlow
+		if options.Smoothing != nil && (!options.Bars || flow) {
+			smoothed, err := smoothedSeries(v, options.Smoothing, options.Period)
+			if err != nil {
+				return fmt.Errorf("error while smoothing %v: %v", v.Name, err), ""
+			}
+			series = append(series, smoothed...)
+		} else if options.Smoothing != nil {
+			// with bars only the flow series get them, the other ones are drawn as their smoothed line
+			line, err := smoothedLine(v, options.Smoothing, options.Period)
+			if err != nil {
+				return fmt.Errorf("error while smoothing %v: %v", v.Name, err), ""
+			}
+			series = append(series, line)
+		} else if options.Bars && flow {
+			series = append(series, barSeries(v))
+		} else {
+			series = append(series, v)
+		}
+	}
+	series = append(series, overlay...)
+	if options.Forecast != nil {
+		bands := make([]chart.Series, 0)
+		for i, v := range *charts {
+			forecastLine, band, err := forecastSeries(v, options.Forecast, i == 0)
+			if err != nil {
+				return fmt.Errorf("error while forecasting %v: %v", v.Name, err), ""
+			}
+			series = append(series, forecastLine)
+			bands = append(bands, band)
+		}
+		// bands go behind the series, right after the background
+		series = append(series[:len(background)], append(bands, series[len(background):]...)...)
+
+		forecastGridLines := append(make([]chart.GridLine, 0), *gridLines...)
+		for day := 1; day <= options.Forecast.Days; day++ {
+			forecastGridLines = *dateXAxis(&forecastGridLines, lastDate(charts).AddDate(0, 0, day))
+		}
+		gridLines = &forecastGridLines
 	}
 	for _, v := range *annotations {
 		series = append(series, v)
 	}
+	var noteLines []string
+	if options.Notes != nil {
+		var markers chart.AnnotationSeries
+		markers, noteLines = noteMarkers(options.Notes, charts, extras, options)
+		series = append(series, markers)
+	}
 	
-	var backgroundColor drawing.Color
-	var fontsColor drawing.Color
-	var colorMode string
-	lightHour, _ := time.Parse("15:04:05", daySwitch)
-	darkHour, _ := time.Parse("15:04:05", nightSwitch)
-	now := time.Now().Hour()
-	if now >= darkHour.Hour() || now < lightHour.Hour() {
-		backgroundColor = chart.ColorBlack
-		fontsColor = chart.ColorWhite
-		colorMode = "dark"
-	} else {
-		backgroundColor = chart.ColorWhite
-		fontsColor = chart.ColorBlack
-		colorMode = "light"
+	theme := options.theme()
+	backgroundColor, fontsColor := theme.Background, theme.Font
+
+	if options.XAxisName != "" {
+		xAxisName = options.XAxisName
 	}
+	if options.YAxisName != "" {
+		yAxisName = options.YAxisName
+	}
+	width, height := options.size()
 
 	graph := chart.Chart{
 		Title:  title,
-		Width:  1280,
-		Height: 720,
+		Width:  width,
+		Height: height,
+		DPI:    options.dpi(),
+		Font:   options.Font,
 		TitleStyle: chart.Style{
 			FontColor: fontsColor,
+			FontSize:  options.titleFontSize(),
 		},
 		Background: chart.Style{
 			FillColor: backgroundColor,
@@ -77,49 +247,1013 @@ func timeseriesChart(charts *[]chart.TimeSeries, gridLines *[]chart.GridLine, an
 			},
 			ValueFormatter: chart.TimeDateValueFormatter,
 			GridMajorStyle: chart.Style{
-				StrokeColor: chart.ColorAlternateGray,
+				StrokeColor: theme.Grid,
 				StrokeWidth: 1.0,
 			},
 			TickStyle: chart.Style{
 				FontColor: fontsColor,
-				FontSize:  15,
+				FontSize:  options.fontSize(),
 			},
 			GridLines: *gridLines,
+			Ticks:     ticks,
 		},
 		YAxis: chart.YAxis{
 			Name: yAxisName,
 			Style: chart.Style{
 				StrokeColor: fontsColor,
 			},
-			ValueFormatter: func(v interface{}) string {
-				return fmt.Sprintf("%d", int(v.(float64)))
-			},
+			ValueFormatter: yValueFormatter,
 			TickStyle: chart.Style{
 				TextRotationDegrees: 45.0,
 				FontColor:           fontsColor,
-				FontSize:            15,
+				FontSize:            options.fontSize(),
 			},
 		},
 		Series: series,
 	}
 
-	graph.Elements = []chart.Renderable{chart.Legend(&graph, chart.Style{
-		FontSize: 15,
-	})}
+	for _, v := range *charts {
+		if v.YAxis == chart.YAxisSecondary {
+			graph.YAxisSecondary = chart.YAxis{
+				Name:           extras.secondaryYAxisName,
+				Style:          graph.YAxis.Style,
+				ValueFormatter: graph.YAxis.ValueFormatter,
+				TickStyle:      graph.YAxis.TickStyle,
+			}
+			break
+		}
+	}
+
+	// a smaller legend keeps many series, like the ones of comparison plots, within the plot
+	entries := legendEntries(series)
+	legendFontSize := options.legendFontSize(len(entries))
+	graph.Elements = []chart.Renderable{}
+	switch options.Legend {
+	case LegendDefault:
+		graph.Elements = append(graph.Elements, chart.Legend(&graph, chart.Style{
+			FontSize: legendFontSize,
+		}))
+	case LegendTopLeft:
+		graph.Elements = append(graph.Elements, chart.Legend(&graph, chart.Style{
+			FillColor:   backgroundColor,
+			FontColor:   fontsColor,
+			StrokeColor: fontsColor,
+			FontSize:    legendFontSize,
+		}))
+	}
+	if extras.subtitle != "" {
+		graph.Background.Padding.Top = 65
+		graph.Elements = append(graph.Elements, subtitleRenderable(&graph, extras.subtitle, fontsColor))
+	}
+	graph.Background.Padding = options.padding(graph.Background.Padding)
+
+	// the plot keeps its size, the legend and the notes are drawn in the space added below it
+	footerHeight := 0
+	if len(noteLines) > 0 {
+		footerHeight = notesFooterHeight(noteLines)
+		graph.Elements = append(graph.Elements, notesFooterRenderable(&graph, noteLines, fontsColor))
+	}
+	if options.Legend == LegendBottom && len(entries) > 0 {
+		graph.Elements = append(graph.Elements, bottomLegendRenderable(&graph, entries, footerHeight, legendFontSize, fontsColor))
+		footerHeight += bottomLegendHeight(entries, graph.Width, legendFontSize, graph.GetDPI())
+	}
+	graph.Height += footerHeight
+	graph.Background.Padding.Bottom += footerHeight
+
+	return saveChart(func(w io.Writer) error {
+		return graph.Render(options.Format.renderer(), w)
+	}, options, title, filename, theme.Name)
+}
+
+// Creates a horizontal bar chart of a ranking, the entry matching highlight by name or sigla gets a different color
+func rankingChart(entries *[]RankEntry, barColor drawing.Color, highlight, subtitle, title, filename string, opts ...ChartOptions) (error, string) {
+	if len(*entries) == 0 {
+		return fmt.Errorf("error while creating ranking chart: empty ranking"), ""
+	}
+	options := getChartOptions(opts)
+	theme := options.theme()
+	backgroundColor, fontsColor := theme.Background, theme.Font
+	highlightColor := drawing.Color{R: 255, G: 150, B: 0, A: 255}
+	width, height := options.size()
+	fontSize := options.fontSize()
+
+	r, err := options.Format.renderer()(width, height)
+	if err != nil {
+		return fmt.Errorf("error while creating renderer: %v", err), ""
+	}
+	if options.DPI > 0 {
+		r.SetDPI(options.DPI)
+	}
+	font := options.Font
+	if font == nil {
+		font, err = chart.GetDefaultFont()
+		if err != nil {
+			return fmt.Errorf("error while loading font: %v", err), ""
+		}
+	}
+
+	chart.Draw.Box(r, chart.Box{Top: 0, Left: 0, Right: width, Bottom: height}, chart.Style{
+		FillColor:   backgroundColor,
+		StrokeColor: backgroundColor,
+		StrokeWidth: 1,
+	})
+
+	// drawing boxes resets the style, so the font is set again before writing
+	r.SetFont(font)
+	r.SetFontColor(fontsColor)
+	r.SetFontSize(options.titleFontSize())
+	titleBox := r.MeasureText(title)
+	top := chart.DefaultTitleTop + titleBox.Height()
+	r.Text(title, (width>>1)-(titleBox.Width()>>1), top)
+	if subtitle != "" {
+		r.SetFontSize(12)
+		subtitleBox := r.MeasureText(subtitle)
+		top += 8 + subtitleBox.Height()
+		r.Text(subtitle, (width>>1)-(subtitleBox.Width()>>1), top)
+	}
+	top += 25
+
+	// names on the left and values at the end of the bars
+	r.SetFontSize(fontSize)
+	labels := make([]string, len(*entries))
+	valueLabels := make([]string, len(*entries))
+	labelsWidth, valuesWidth := 0, 0
+	minValue, maxValue := 0.0, 0.0
+	for i, v := range *entries {
+		labels[i] = fmt.Sprintf("%d. %v", v.Posizione, v.Denominazione)
+		valueLabels[i] = yValueFormatter(v.Valore)
+		labelsWidth = chart.MaxInt(labelsWidth, r.MeasureText(labels[i]).Width())
+		valuesWidth = chart.MaxInt(valuesWidth, r.MeasureText(valueLabels[i]).Width())
+		minValue = math.Min(minValue, v.Valore)
+		maxValue = math.Max(maxValue, v.Valore)
+	}
+	if maxValue == minValue {
+		maxValue = minValue + 1
+	}
+
+	left := 20 + labelsWidth + 10
+	right := width - 20 - valuesWidth - 10
+	bottom := height - 20
+	scale := func(value float64) int {
+		return left + int(float64(right-left)*(value-minValue)/(maxValue-minValue))
+	}
+	zero := scale(0)
+	rowHeight := math.Min(float64(bottom-top)/float64(len(*entries)), 60)
+
+	for i, v := range *entries {
+		rowTop := top + int(float64(i)*rowHeight)
+		barTop := rowTop + int(rowHeight*0.125)
+		barBottom := rowTop + int(rowHeight*0.875)
+		textY := rowTop + int(rowHeight/2) + (r.MeasureText(labels[i]).Height() >> 1)
+
+		color := barColor
+		if highlight != "" && (sameAreaName(v.Denominazione, highlight) || (v.Sigla != "" && strings.EqualFold(v.Sigla, highlight))) {
+			color = highlightColor
+		}
+		barLeft, barRight := zero, scale(v.Valore)
+		if v.Valore < 0 {
+			barLeft, barRight = barRight, zero
+		}
+		chart.Draw.Box(r, chart.Box{Top: barTop, Left: barLeft, Right: barRight, Bottom: barBottom}, chart.Style{
+			FillColor:   color,
+			StrokeColor: color,
+			StrokeWidth: 1,
+		})
+
+		r.SetFont(font)
+		r.SetFontColor(fontsColor)
+		r.SetFontSize(fontSize)
+		r.Text(labels[i], left-10-r.MeasureText(labels[i]).Width(), textY)
+		r.Text(valueLabels[i], barRight+8, textY)
+	}
+
+	// axis at zero
+	r.SetStrokeColor(fontsColor)
+	r.SetStrokeWidth(1)
+	r.MoveTo(zero, top)
+	r.LineTo(zero, bottom)
+	r.Stroke()
+
+	return saveChart(r.Save, options, title, filename, theme.Name)
+}
+
+// Formats Y axis values, keeping decimals only for small non integer values
+func yValueFormatter(v interface{}) string {
+	value := v.(float64)
+	if value == math.Trunc(value) || math.Abs(value) >= 100 {
+		return fmt.Sprintf("%d", int(value))
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+// Creates the time series of a field with the color it is usually plotted with
+func fieldTimeseries(fieldName string, xValues *[]time.Time, yValues *[]float64) (chart.TimeSeries, error) {
+	color, err := fieldColor(fieldName)
+	if err != nil {
+		return chart.TimeSeries{}, err
+	}
+
+	return chart.TimeSeries{
+		Name: fieldName,
+		Style: chart.Style{
+			StrokeColor: color,
+			FillColor:   color.WithAlpha(200),
+		},
+		YAxis:   0,
+		XValues: *xValues,
+		YValues: *yValues,
+	}, nil
+}
+
+// Colored period drawn behind the plot series
+type backgroundPeriod struct {
+	start time.Time
+	end   time.Time
+}
+
+// Series coloring the background of the given periods, named after what the color means
+type backgroundSeries struct {
+	name    string
+	color   drawing.Color
+	periods []backgroundPeriod
+}
+
+// Returns the name of the series
+func (bs backgroundSeries) GetName() string {
+	return bs.name
+}
+
+// Returns the style of the series, used by the legend
+func (bs backgroundSeries) GetStyle() chart.Style {
+	return chart.Style{
+		StrokeColor: bs.color,
+		StrokeWidth: 10,
+	}
+}
+
+// Returns the Y axis of the series
+func (bs backgroundSeries) GetYAxis() chart.YAxisType {
+	return chart.YAxisPrimary
+}
+
+// Validates the series
+func (bs backgroundSeries) Validate() error {
+	return nil
+}
+
+// Draws the periods as boxes as high as the canvas
+func (bs backgroundSeries) Render(r chart.Renderer, canvasBox chart.Box, xrange, yrange chart.Range, defaults chart.Style) {
+	style := chart.Style{
+		FillColor:   bs.color,
+		StrokeColor: bs.color,
+		StrokeWidth: 1,
+	}
+	for _, p := range bs.periods {
+		left := canvasBox.Left + xrange.Translate(chart.TimeToFloat64(p.start))
+		right := canvasBox.Left + xrange.Translate(chart.TimeToFloat64(p.end))
+		left = chart.MaxInt(left, canvasBox.Left)
+		right = chart.MinInt(right, canvasBox.Right)
+		if right <= left {
+			continue
+		}
+		chart.Draw.Box(r, chart.Box{
+			Top:    canvasBox.Top,
+			Left:   left,
+			Right:  right,
+			Bottom: canvasBox.Bottom,
+		}, style)
+	}
+}
+
+// Groups consecutive days with the same label into background series, one per label
+func daysToBackgroundSeries(dates *[]time.Time, labels []string, colors map[string]drawing.Color) []chart.Series {
+	order := make([]string, 0)
+	periods := make(map[string][]backgroundPeriod)
+	for i, date := range *dates {
+		label := labels[i]
+		if _, ok := colors[label]; !ok {
+			continue
+		}
+		if _, ok := periods[label]; !ok {
+			order = append(order, label)
+		}
+
+		start := date.Add(-12 * time.Hour)
+		end := date.Add(12 * time.Hour)
+		labelPeriods := periods[label]
+		if i > 0 && labels[i-1] == label && len(labelPeriods) > 0 {
+			labelPeriods[len(labelPeriods)-1].end = end
+		} else {
+			labelPeriods = append(labelPeriods, backgroundPeriod{start: start, end: end})
+		}
+		periods[label] = labelPeriods
+	}
+
+	series := make([]chart.Series, 0)
+	for _, label := range order {
+		series = append(series, backgroundSeries{
+			name:    label,
+			color:   colors[label],
+			periods: periods[label],
+		})
+	}
+
+	return series
+}
+
+// Series filling the area between a lower and an upper bound
+type bandSeries struct {
+	name    string
+	color   drawing.Color
+	yAxis   chart.YAxisType
+	xValues []time.Time
+	lower   []float64
+	upper   []float64
+}
+
+// Returns the name of the series
+func (bs bandSeries) GetName() string {
+	return bs.name
+}
+
+// Returns the style of the series, used by the legend
+func (bs bandSeries) GetStyle() chart.Style {
+	return chart.Style{
+		StrokeColor: bs.color,
+		StrokeWidth: 10,
+	}
+}
+
+// Returns the Y axis of the series
+func (bs bandSeries) GetYAxis() chart.YAxisType {
+	return bs.yAxis
+}
+
+// Validates the series
+func (bs bandSeries) Validate() error {
+	if len(bs.xValues) != len(bs.lower) || len(bs.xValues) != len(bs.upper) {
+		return fmt.Errorf("band bounds and dates have different lengths")
+	}
+	return nil
+}
+
+// Returns the number of points of the band
+func (bs bandSeries) Len() int {
+	return len(bs.xValues)
+}
+
+// Returns the date and the bounds of a point of the band
+func (bs bandSeries) GetBoundedValues(index int) (x, y1, y2 float64) {
+	return chart.TimeToFloat64(bs.xValues[index]), bs.upper[index], bs.lower[index]
+}
+
+// Draws the band
+func (bs bandSeries) Render(r chart.Renderer, canvasBox chart.Box, xrange, yrange chart.Range, defaults chart.Style) {
+	if len(bs.xValues) == 0 {
+		return
+	}
+	chart.Draw.BoundedSeries(r, canvasBox, xrange, yrange, chart.Style{
+		FillColor:   bs.color,
+		StrokeColor: bs.color,
+		StrokeWidth: 1,
+	}, bs)
+}
+
+// Returns the series stacked on each other, each one ending at the sum of its values and of the ones of the series before it.
+// Normalized series end at the percent of the sum of all of them, days without any value are left at zero
+func stackCharts(charts *[]chart.TimeSeries, normalized bool) (*[]chart.TimeSeries, error) {
+	stacked := make([]chart.TimeSeries, len(*charts))
+	if len(*charts) == 0 {
+		return &stacked, nil
+	}
+
+	days := len((*charts)[0].XValues)
+	totals := make([]float64, days)
+	for i, v := range *charts {
+		if len(v.XValues) != days || len(v.YValues) != days {
+			return nil, fmt.Errorf("series %v has a different number of days", v.Name)
+		}
+		stacked[i] = v
+		stacked[i].YValues = make([]float64, days)
+		for day, value := range v.YValues {
+			totals[day] += value
+			stacked[i].YValues[day] = totals[day]
+		}
+	}
+
+	if normalized {
+		for i := range stacked {
+			for day := range stacked[i].YValues {
+				if totals[day] == 0 {
+					stacked[i].YValues[day] = 0
+					continue
+				}
+				stacked[i].YValues[day] = stacked[i].YValues[day] * 100 / totals[day]
+			}
+		}
+	}
+
+	return &stacked, nil
+}
+
+// Returns the area of the i-th stacked series, from the top of the series below it
+func stackedBand(charts *[]chart.TimeSeries, i int) bandSeries {
+	current := (*charts)[i]
+	lower := make([]float64, len(current.YValues))
+	if i > 0 {
+		copy(lower, (*charts)[i-1].YValues)
+	}
+
+	return bandSeries{
+		name:    current.Name,
+		color:   current.Style.StrokeColor,
+		yAxis:   current.YAxis,
+		xValues: current.XValues,
+		lower:   lower,
+		upper:   current.YValues,
+	}
+}
+
+// Series drawing a bar for each day
+type timeBarSeries struct {
+	name    string
+	style   chart.Style
+	yAxis   chart.YAxisType
+	xValues []time.Time
+	yValues []float64
+}
+
+// Returns the name of the series
+func (ts timeBarSeries) GetName() string {
+	return ts.name
+}
+
+// Returns the style of the series
+func (ts timeBarSeries) GetStyle() chart.Style {
+	return ts.style
+}
+
+// Returns the Y axis of the series
+func (ts timeBarSeries) GetYAxis() chart.YAxisType {
+	return ts.yAxis
+}
+
+// Validates the series
+func (ts timeBarSeries) Validate() error {
+	if len(ts.xValues) != len(ts.yValues) {
+		return fmt.Errorf("bar values and dates have different lengths")
+	}
+	return nil
+}
+
+// Returns the number of bars
+func (ts timeBarSeries) Len() int {
+	return len(ts.xValues)
+}
+
+// Returns the date and the bounds of a bar, which always starts from zero
+func (ts timeBarSeries) GetBoundedValues(index int) (x, y1, y2 float64) {
+	return chart.TimeToFloat64(ts.xValues[index]), ts.yValues[index], 0
+}
+
+// Draws the bars
+func (ts timeBarSeries) Render(r chart.Renderer, canvasBox chart.Box, xrange, yrange chart.Range, defaults chart.Style) {
+	if len(ts.xValues) == 0 {
+		return
+	}
+
+	style := chart.Style{
+		FillColor:   ts.style.GetFillColor(ts.style.GetStrokeColor()),
+		StrokeColor: ts.style.GetFillColor(ts.style.GetStrokeColor()),
+		StrokeWidth: 1,
+	}
 
-	if filename==""{
-		filename=title+"-"+colorMode+".png"
+	// bars leave a fifth of the space of a day empty
+	barWidth := canvasBox.Width() * 4 / 5
+	if len(ts.xValues) > 1 {
+		first := xrange.Translate(chart.TimeToFloat64(ts.xValues[0]))
+		last := xrange.Translate(chart.TimeToFloat64(ts.xValues[len(ts.xValues)-1]))
+		barWidth = (last - first) * 4 / (5 * (len(ts.xValues) - 1))
 	}
-	f, err := os.Create(filename)
+	if barWidth < 1 {
+		barWidth = 1
+	}
+
+	zero := canvasBox.Bottom - yrange.Translate(0)
+	for i, v := range ts.yValues {
+		x := canvasBox.Left + xrange.Translate(chart.TimeToFloat64(ts.xValues[i]))
+		y := canvasBox.Bottom - yrange.Translate(v)
+		top, bottom := y, zero
+		if v < 0 {
+			top, bottom = zero, y
+		}
+		// bars at the edges are cut to stay within the canvas
+		left := x - barWidth/2
+		if left < canvasBox.Left {
+			left = canvasBox.Left
+		}
+		right := x + barWidth - barWidth/2
+		if right > canvasBox.Right {
+			right = canvasBox.Right
+		}
+		chart.Draw.Box(r, chart.Box{
+			Top:    top,
+			Left:   left,
+			Right:  right,
+			Bottom: bottom,
+		}, style)
+	}
+}
+
+// Turns a time series into bars of its values, in the color of its line
+func barSeries(ts chart.TimeSeries) timeBarSeries {
+	return timeBarSeries{
+		name: ts.Name,
+		style: chart.Style{
+			StrokeColor: ts.Style.StrokeColor,
+			FillColor:   ts.Style.StrokeColor.WithAlpha(140),
+			StrokeWidth: 10,
+		},
+		yAxis:   ts.YAxis,
+		xValues: ts.XValues,
+		yValues: ts.YValues,
+	}
+}
+
+// Turns a time series into bars of the raw values with the line of the smoothed values on top
+func smoothedSeries(ts chart.TimeSeries, smoothing *Smoothing, period Period) ([]chart.Series, error) {
+	line, err := smoothedLine(ts, smoothing, period)
 	if err != nil {
-		return fmt.Errorf("error while creating file: %v", err), ""
+		return nil, err
 	}
-	defer f.Close()
-	err = graph.Render(chart.PNG, f)
+
+	return []chart.Series{barSeries(ts), line}, nil
+}
+
+// Returns the line of the smoothed values of a time series, named after the smoothing
+func smoothedLine(ts chart.TimeSeries, smoothing *Smoothing, period Period) (chart.TimeSeries, error) {
+	smoothed, err := smoothing.Apply(&ts.YValues)
 	if err != nil {
-		return fmt.Errorf("error while rendering graph: %v", err), ""
+		return chart.TimeSeries{}, err
+	}
+
+	return chart.TimeSeries{
+		Name: ts.Name + " (" + smoothing.describe(period) + ")",
+		Style: chart.Style{
+			StrokeColor: ts.Style.StrokeColor,
+			StrokeWidth: 3,
+		},
+		YAxis:   ts.YAxis,
+		XValues: ts.XValues,
+		YValues: *smoothed,
+	}, nil
+}
+
+// Aggregates the series by period according to their kinds, ratios with known parts as the ratio of their sums
+func resampleCharts(charts *[]chart.TimeSeries, extras *plotExtras, period Period) (*[]chart.TimeSeries, error) {
+	resampled := make([]chart.TimeSeries, len(*charts))
+	for i, v := range *charts {
+		var xValues *[]time.Time
+		var yValues *[]float64
+		var err error
+		if parts, ok := extras.ratios[i]; ok {
+			xValues, yValues, err = ResampleRatio(&v.XValues, &parts.numerators, &parts.denominators, period)
+		} else {
+			xValues, yValues, err = Resample(&v.XValues, &v.YValues, seriesKind(i, v, extras.kinds), period)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", v.Name, err)
+		}
+		resampled[i] = v
+		resampled[i].XValues = *xValues
+		resampled[i].YValues = *yValues
+	}
+
+	return &resampled, nil
+}
+
+// Returns the series per 100.000 inhabitants of the area of the plot, leaving rates as they are,
+// along with the axes of the scaled series and the year of the population used
+func perCapitaCharts(charts *[]chart.TimeSeries, extras *plotExtras, year int) (*[]chart.TimeSeries, map[chart.YAxisType]bool, int, error) {
+	scaled := make([]chart.TimeSeries, len(*charts))
+	axes := make(map[chart.YAxisType]bool)
+	population, populationYear := 0, 0
+	for i, v := range *charts {
+		scaled[i] = v
+		if seriesKind(i, v, extras.kinds) == FieldRate {
+			continue
+		}
+		if population == 0 {
+			var err error
+			population, populationYear, err = areaPopulation(extras.plotArea, year)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		scaled[i].YValues = *per100k(&v.YValues, population)
+		axes[v.YAxis] = true
 	}
-	return nil, filename
+
+	return &scaled, axes, populationYear, nil
+}
+
+// Returns the name of an axis of values per 100.000 inhabitants
+func perCapitaAxisName(name string) string {
+	if name == "" {
+		return "Per 100.000 abitanti"
+	}
+	return name + " per 100.000 abitanti"
+}
+
+// Aggregates by period the lines and the bands drawn along the series, averaging their values.
+// Shaded days cannot be aggregated, as a period may hold days with different labels
+func resampleDecorations(decorations []chart.Series, period Period) ([]chart.Series, error) {
+	resampled := make([]chart.Series, 0)
+	for _, v := range decorations {
+		switch d := v.(type) {
+		case chart.TimeSeries:
+			xValues, yValues, err := Resample(&d.XValues, &d.YValues, FieldRate, period)
+			if err != nil {
+				return nil, err
+			}
+			d.XValues, d.YValues = *xValues, *yValues
+			resampled = append(resampled, d)
+		case bandSeries:
+			xValues, lower, err := Resample(&d.xValues, &d.lower, FieldRate, period)
+			if err != nil {
+				return nil, err
+			}
+			_, upper, err := Resample(&d.xValues, &d.upper, FieldRate, period)
+			if err != nil {
+				return nil, err
+			}
+			d.xValues, d.lower, d.upper = *xValues, *lower, *upper
+			resampled = append(resampled, d)
+		case backgroundSeries:
+			return nil, fmt.Errorf("the days shaded as %v cannot be aggregated by period", d.name)
+		default:
+			return nil, fmt.Errorf("series %v cannot be aggregated by period", v.GetName())
+		}
+	}
+
+	return resampled, nil
+}
+
+// Returns the kind of the i-th series of a plot, series without one are looked up by name and taken as rates when unknown
+func seriesKind(i int, series chart.TimeSeries, kinds []FieldKind) FieldKind {
+	if i < len(kinds) {
+		return kinds[i]
+	}
+	if kind, err := GetFieldKind(series.Name); err == nil {
+		return kind
+	}
+
+	return FieldRate
+}
+
+// Returns the background series shading the waves, each labelled with its number and peak
+func wavesBackground(waves *[]Wave) []chart.Series {
+	colors := []drawing.Color{
+		{R: 255, G: 150, B: 0, A: 50},
+		{R: 31, G: 119, B: 180, A: 50},
+		{R: 44, G: 160, B: 44, A: 50},
+	}
+
+	series := make([]chart.Series, 0)
+	for i, v := range *waves {
+		name := fmt.Sprintf("%dª ondata, picco il %v", v.Numero, v.Picco.Format("02/01/2006"))
+		if v.InCorso {
+			name += ", in corso"
+		}
+		series = append(series, backgroundSeries{
+			name:    name,
+			color:   colors[i%len(colors)],
+			periods: []backgroundPeriod{{start: v.Inizio.Add(-12 * time.Hour), end: v.Fine.Add(12 * time.Hour)}},
+		})
+	}
+
+	return series
+}
+
+// Returns a grid line for each period of the series and the labels of at most a dozen of them
+func periodXAxis(charts *[]chart.TimeSeries, period Period) (*[]chart.GridLine, []chart.Tick) {
+	starts := make([]time.Time, 0)
+	seen := make(map[time.Time]bool)
+	for _, v := range *charts {
+		for _, x := range v.XValues {
+			if !seen[x] {
+				seen[x] = true
+				starts = append(starts, x)
+			}
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	gridLines := make([]chart.GridLine, 0)
+	ticks := make([]chart.Tick, 0)
+	step := (len(starts) + 11) / 12
+	for i, v := range starts {
+		gridLines = *dateXAxis(&gridLines, v)
+		if i%step == 0 {
+			ticks = append(ticks, chart.Tick{Value: chart.TimeToFloat64(v), Label: periodLabel(v, period)})
+		}
+	}
+	// the ticks set the range of the axis, an unlabelled one keeps the last period within it
+	if len(starts) > 0 && (len(starts)-1)%step != 0 {
+		ticks = append(ticks, chart.Tick{Value: chart.TimeToFloat64(starts[len(starts)-1])})
+	}
+
+	return &gridLines, ticks
+}
+
+// Returns the last date among the series
+func lastDate(charts *[]chart.TimeSeries) time.Time {
+	var last time.Time
+	for _, v := range *charts {
+		if len(v.XValues) > 0 && v.XValues[len(v.XValues)-1].After(last) {
+			last = v.XValues[len(v.XValues)-1]
+		}
+	}
+
+	return last
+}
+
+// Creates the dashed continuation of a time series with the band of its prediction interval,
+// the band is named only when it has to appear in the legend
+func forecastSeries(ts chart.TimeSeries, config *ForecastConfig, namedBand bool) (chart.TimeSeries, bandSeries, error) {
+	forecasts, err := ForecastSeries(&ts.XValues, &ts.YValues, *config)
+	if err != nil {
+		return chart.TimeSeries{}, bandSeries{}, err
+	}
+
+	// the line starts from the last observed value to look like a continuation
+	xValues := []time.Time{ts.XValues[len(ts.XValues)-1]}
+	yValues := []float64{ts.YValues[len(ts.YValues)-1]}
+	lower := []float64{yValues[0]}
+	upper := []float64{yValues[0]}
+	for _, v := range *forecasts {
+		xValues = append(xValues, v.Data)
+		yValues = append(yValues, v.Previsione)
+		lower = append(lower, v.Inferiore)
+		upper = append(upper, v.Superiore)
+	}
+
+	line := chart.TimeSeries{
+		Name: ts.Name + " (previsione)",
+		Style: chart.Style{
+			StrokeColor:     ts.Style.StrokeColor,
+			StrokeWidth:     3,
+			StrokeDashArray: []float64{10, 6},
+		},
+		YAxis:   ts.YAxis,
+		XValues: xValues,
+		YValues: yValues,
+	}
+	band := bandSeries{
+		color:   ts.Style.StrokeColor.WithAlpha(60),
+		yAxis:   ts.YAxis,
+		xValues: xValues,
+		lower:   lower,
+		upper:   upper,
+	}
+	if namedBand {
+		band.name = fmt.Sprintf("Intervallo di previsione %.0f%% (%v)", config.PredictionInterval*100, config.Method)
+	}
+
+	return line, band, nil
+}
+
+// Creates a dashed horizontal line at the given value spanning the given dates
+func referenceLine(name string, value float64, color drawing.Color, xValues *[]time.Time) chart.TimeSeries {
+	first := (*xValues)[0]
+	last := (*xValues)[len(*xValues)-1]
+	return chart.TimeSeries{
+		Name: name,
+		Style: chart.Style{
+			StrokeColor:     color,
+			StrokeWidth:     2,
+			StrokeDashArray: []float64{10, 6},
+		},
+		YAxis:   0,
+		XValues: []time.Time{first, last},
+		YValues: []float64{value, value},
+	}
+}
+
+// Returns the background colors of the zones
+func zoneColors() map[string]drawing.Color {
+	return map[string]drawing.Color{
+		"zona " + ZonaBianca.String():    {R: 200, G: 200, B: 200, A: 90},
+		"zona " + ZonaGialla.String():    {R: 255, G: 215, B: 0, A: 90},
+		"zona " + ZonaArancione.String(): {R: 255, G: 140, B: 0, A: 90},
+		"zona " + ZonaRossa.String():     {R: 220, G: 20, B: 20, A: 90},
+	}
+}
+
+// Draws a subtitle centered under the plot title
+func subtitleRenderable(graph *chart.Chart, subtitle string, fontColor drawing.Color) chart.Renderable {
+	return func(r chart.Renderer, canvasBox chart.Box, defaults chart.Style) {
+		r.SetFont(graph.TitleStyle.GetFont(defaults.GetFont()))
+		r.SetFontSize(graph.TitleStyle.GetFontSize(chart.DefaultTitleFontSize))
+		titleBox := r.MeasureText(graph.Title)
+
+		r.SetFontSize(12)
+		r.SetFontColor(fontColor)
+		textBox := r.MeasureText(subtitle)
+		x := (graph.GetWidth() >> 1) - (textBox.Width() >> 1)
+		y := chart.DefaultTitleTop + titleBox.Height() + 8 + textBox.Height()
+		r.Text(subtitle, x, y)
+	}
+}
+
+// Maximum number of notes listed below a plot
+const maxNoteLines = 12
+
+// Returns the numbered markers of the notes about the area of the plot, placed on its first series,
+// along with the lines describing them. Notes of days missing from the plot are left out
+func noteMarkers(notes *[]NoteData, charts *[]chart.TimeSeries, extras *plotExtras, options ChartOptions) (chart.AnnotationSeries, []string) {
+	period := options.Period
+	markers := chart.AnnotationSeries{Annotations: make([]chart.Value2, 0)}
+	lines := make([]string, 0)
+	if len(*charts) == 0 {
+		return markers, lines
+	}
+	reference := (*charts)[0]
+	markers.YAxis = reference.YAxis
+
+	type datedNote struct {
+		date time.Time
+		note NoteData
+	}
+	matching := make([]datedNote, 0)
+	for _, n := range *notes {
+		if !noteMatchesArea(n, extras.regionName, extras.provinceName, options.ProvinceNotes) {
+			continue
+		}
+		date, err := noteDate(n)
+		if err != nil {
+			continue
+		}
+		matching = append(matching, datedNote{date: date, note: n})
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].date.Before(matching[j].date)
+	})
+
+	// notes of the same day share one marker listing all their numbers
+	numbers := make(map[int][]string)
+	order := make([]int, 0)
+	for _, v := range matching {
+		start := periodStart(v.date, period)
+		index := -1
+		for i, x := range reference.XValues {
+			if periodStart(x, period).Equal(start) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			continue
+		}
+
+		number := len(lines) + 1
+		if _, ok := numbers[index]; !ok {
+			order = append(order, index)
+		}
+		numbers[index] = append(numbers[index], strconv.Itoa(number))
+
+		area := v.note.Provincia
+		if area == "" {
+			area = v.note.Regione
+		}
+		if area != "" {
+			area = ", " + area
+		}
+		lines = append(lines, fmt.Sprintf("%d. %v%v: %v", number, v.date.Format("02/01/2006"), area, noteText(v.note)))
+	}
+
+	markerColor := drawing.Color{R: 255, G: 150, B: 0, A: 255}
+	for _, i := range order {
+		markers.Annotations = append(markers.Annotations, chart.Value2{
+			Style: chart.Style{
+				FillColor:   markerColor,
+				StrokeColor: markerColor,
+				FontColor:   chart.ColorBlack,
+			},
+			Label:  strings.Join(numbers[i], ","),
+			XValue: chart.TimeToFloat64(reference.XValues[i]),
+			YValue: reference.YValues[i],
+		})
+	}
+
+	if len(lines) > maxNoteLines {
+		lines = append(lines[:maxNoteLines-1], fmt.Sprintf("... e altre %d note", len(lines)-maxNoteLines+1))
+	}
+
+	return markers, lines
+}
+
+// Returns the height of the space below the plot listing the notes
+func notesFooterHeight(lines []string) int {
+	return 30 + 18*len(lines)
+}
+
+// Returns a renderable listing the notes below the plot, each line cut to the width of the plot
+func notesFooterRenderable(graph *chart.Chart, lines []string, fontColor drawing.Color) chart.Renderable {
+	return func(r chart.Renderer, canvasBox chart.Box, defaults chart.Style) {
+		r.SetFont(defaults.GetFont())
+		r.SetFontSize(12)
+		r.SetFontColor(fontColor)
+
+		maxWidth := graph.GetWidth() - 40
+		y := graph.GetHeight() - notesFooterHeight(lines) + 28
+		for _, line := range lines {
+			text := line
+			for runes := []rune(line); len(runes) > 0 && r.MeasureText(text).Width() > maxWidth; {
+				runes = runes[:len(runes)-1]
+				text = string(runes) + "..."
+			}
+			r.Text(text, 20, y)
+			y += 18
+		}
+	}
+}
+
+// Returns the color used to plot the specified field
+func fieldColor(fieldName string) (drawing.Color, error) {
+	switch strings.ToLower(fieldName) {
+	case "ricoverati_con_sintomi":
+		return drawing.Color{R: 38, G: 224, B: 175, A: 255}, nil
+	case "terapia_intensiva":
+		return drawing.Color{R: 88, G: 22, B: 115, A: 255}, nil
+	case "totale_ospedalizzati":
+		return drawing.Color{R: 171, G: 213, B: 255, A: 255}, nil
+	case "isolamento_domiciliare":
+		return drawing.Color{R: 171, G: 213, B: 255, A: 255}, nil
+	case "attualmente_positivi":
+		return drawing.Color{R: 237, G: 164, B: 17, A: 255}, nil
+	case "nuovi_positivi":
+		return drawing.Color{R: 18, G: 4, B: 217, A: 255}, nil
+	case "dimessi_guariti":
+		return drawing.Color{R: 38, G: 224, B: 175, A: 255}, nil
+	case "deceduti":
+		return chart.ColorAlternateGray, nil
+	case "totale_casi":
+		return drawing.Color{R: 255, A: 255}, nil
+	case "tamponi":
+		return drawing.Color{R: 175, G: 232, B: 169, A: 255}, nil
+	case "nuovi_tamponi":
+		return drawing.Color{R: 110, G: 180, B: 100, A: 255}, nil
+	case "nuovi_deceduti":
+		return drawing.Color{R: 90, G: 90, B: 90, A: 255}, nil
+	case "tasso_positivita":
+		return drawing.Color{R: 214, G: 39, B: 40, A: 255}, nil
+	case "tasso_positivita_molecolare":
+		return drawing.Color{R: 148, G: 103, B: 189, A: 255}, nil
+	case "tasso_positivita_antigenico":
+		return drawing.Color{R: 23, G: 190, B: 207, A: 255}, nil
+	case "letalita_apparente":
+		return drawing.Color{R: 80, G: 80, B: 80, A: 255}, nil
+	case "quota_ospedalizzati":
+		return drawing.Color{R: 255, G: 127, B: 14, A: 255}, nil
+	case "quota_terapia_intensiva":
+		return drawing.Color{R: 88, G: 22, B: 115, A: 255}, nil
+	default:
+		if _, ok := fatalityLag(fieldName); ok {
+			return drawing.Color{R: 140, G: 86, B: 75, A: 255}, nil
+		}
+		return drawing.Color{}, fmt.Errorf("wrong field name passed")
+	}
+}
+
+// Colors of the lines of comparison plots
+var comparisonColors = []drawing.Color{
+	{R: 31, G: 119, B: 180, A: 255},
+	{R: 255, G: 127, B: 14, A: 255},
+	{R: 44, G: 160, B: 44, A: 255},
+	{R: 214, G: 39, B: 40, A: 255},
+	{R: 148, G: 103, B: 189, A: 255},
+	{R: 140, G: 86, B: 75, A: 255},
+	{R: 227, G: 119, B: 194, A: 255},
+	{R: 127, G: 127, B: 127, A: 255},
+	{R: 188, G: 189, B: 34, A: 255},
+	{R: 23, G: 190, B: 207, A: 255},
+	{R: 18, G: 4, B: 217, A: 255},
+}
+
+// Returns the style of the i-th line of a comparison plot, lines after the colors of the palette run out are dashed
+func comparisonStyle(i int, palette []drawing.Color) chart.Style {
+	style := chart.Style{
+		StrokeColor: palette[i%len(palette)],
+		StrokeWidth: 2,
+	}
+	if (i/len(palette))%2 == 1 {
+		style.StrokeDashArray = []float64{6, 4}
+	}
+
+	return style
 }
 
 // Converts dates to Float64 to fit the X Axis of the plots
@@ -143,8 +1277,6 @@ func nationToTimeseries(data *[]NationData, fieldName string, index int) (*[]tim
 		}
 		year, month, day := dateRead.Date()
 		dateRead = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-		date = append(date, dateRead)
-		dateAxis = *dateXAxis(&dateAxis, dateRead)
 
 		switch strings.ToLower(fieldName) {
 		case "ricoverati_con_sintomi":
@@ -177,14 +1309,56 @@ func nationToTimeseries(data *[]NationData, fieldName string, index int) (*[]tim
 		case "tamponi":
 			values = append(values, float64((*data)[i].Tamponi))
 			break
+		case "nuovi_tamponi":
+			if i == 0 {
+				values = append(values, float64((*data)[i].Tamponi))
+			} else {
+				values = append(values, float64((*data)[i].Tamponi-(*data)[i-1].Tamponi))
+			}
+			break
+		case "nuovi_deceduti":
+			if i == 0 {
+				values = append(values, float64((*data)[i].Deceduti))
+			} else {
+				values = append(values, float64((*data)[i].Deceduti-(*data)[i-1].Deceduti))
+			}
+			break
+		case "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico":
+			rate, ok := nationPositivityRate(data, i, fieldName)
+			if !ok {
+				continue
+			}
+			values = append(values, rate)
+			break
 		default:
-			return nil, nil, nil, fmt.Errorf("wrong field name passed")
+			if !isRatioField(fieldName) {
+				return nil, nil, nil, fmt.Errorf("wrong field name passed")
+			}
+			value, ok := nationRatio(data, i, fieldName)
+			if !ok {
+				continue
+			}
+			values = append(values, value)
 		}
+
+		date = append(date, dateRead)
+		dateAxis = *dateXAxis(&dateAxis, dateRead)
 	}
 
 	return &date, &values, &dateAxis, nil
 }
 
+// Returns the first index not before the given one of the region with the given code index in the regional data
+func regionFirstIndex(index int, startRegionCodeIndex int) int {
+	offset := index % 21
+	if offset == startRegionCodeIndex {
+		return index
+	} else if offset > startRegionCodeIndex {
+		return index + 21 - (offset - startRegionCodeIndex)
+	}
+	return index + (startRegionCodeIndex - offset)
+}
+
 // Creates series of points according to the regional data
 func regionToTimeseries(data *[]RegionData, fieldName string, index int, startRegionCodeIndex int) (*[]time.Time, *[]float64, *[]chart.GridLine, error) {
 	if startRegionCodeIndex < 0 || startRegionCodeIndex > 21 {
@@ -195,25 +1369,13 @@ func regionToTimeseries(data *[]RegionData, fieldName string, index int, startRe
 	values := make([]float64, 0)
 	dateAxis := make([]chart.GridLine, 0)
 
-	offset := index % 21
-	var realIndex int
-	if offset == startRegionCodeIndex {
-		realIndex = index
-	} else if offset > startRegionCodeIndex {
-		realIndex = index + 21 - (offset - startRegionCodeIndex)
-	} else {
-		realIndex = index + (startRegionCodeIndex - offset)
-	}
-
-	for i := realIndex; i < len(*data); i += 21 {
+	for i := regionFirstIndex(index, startRegionCodeIndex); i < len(*data); i += 21 {
 		dateRead, err := time.Parse("2006-01-02T15:04:05", (*data)[i].Data)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("error converting date string to date: %v", err)
 		}
 		year, month, day := dateRead.Date()
 		dateRead = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-		date = append(date, dateRead)
-		dateAxis = *dateXAxis(&dateAxis, dateRead)
 
 		switch strings.ToLower(fieldName) {
 		case "ricoverati_con_sintomi":
@@ -246,9 +1408,40 @@ func regionToTimeseries(data *[]RegionData, fieldName string, index int, startRe
 		case "tamponi":
 			values = append(values, float64((*data)[i].Tamponi))
 			break
+		case "nuovi_tamponi":
+			if i < 21 {
+				values = append(values, float64((*data)[i].Tamponi))
+			} else {
+				values = append(values, float64((*data)[i].Tamponi-(*data)[i-21].Tamponi))
+			}
+			break
+		case "nuovi_deceduti":
+			if i < 21 {
+				values = append(values, float64((*data)[i].Deceduti))
+			} else {
+				values = append(values, float64((*data)[i].Deceduti-(*data)[i-21].Deceduti))
+			}
+			break
+		case "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico":
+			rate, ok := regionPositivityRate(data, i, fieldName)
+			if !ok {
+				continue
+			}
+			values = append(values, rate)
+			break
 		default:
-			return nil, nil, nil, fmt.Errorf("wrong field name passed")
+			if !isRatioField(fieldName) {
+				return nil, nil, nil, fmt.Errorf("wrong field name passed")
+			}
+			value, ok := regionRatio(data, i, fieldName)
+			if !ok {
+				continue
+			}
+			values = append(values, value)
 		}
+
+		date = append(date, dateRead)
+		dateAxis = *dateXAxis(&dateAxis, dateRead)
 	}
 
 	return &date, &values, &dateAxis, nil
@@ -0,0 +1,55 @@
+package covidgraphs
+
+import (
+	"testing"
+
+	"github.com/wcharczuk/go-chart"
+)
+
+func TestStackCharts(t *testing.T) {
+	days := testDays(3)
+	charts := []chart.TimeSeries{
+		{Name: "a", XValues: days, YValues: []float64{1, 0, 2}},
+		{Name: "b", XValues: days, YValues: []float64{3, 0, 2}},
+	}
+
+	tests := []struct {
+		name       string
+		normalized bool
+		want       [][]float64
+	}{
+		{"absolute", false, [][]float64{{1, 0, 2}, {4, 0, 4}}},
+		{"normalized", true, [][]float64{{25, 0, 50}, {100, 0, 100}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stacked, err := stackCharts(&charts, test.normalized)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, v := range *stacked {
+				for day, value := range v.YValues {
+					if !sameValue(value, test.want[i][day]) {
+						t.Fatalf("series %v is %v, want %v", v.Name, v.YValues, test.want[i])
+					}
+				}
+			}
+		})
+	}
+
+	// the series given are left as they are
+	if charts[1].YValues[0] != 3 {
+		t.Fatalf("stacking changed the given series to %v", charts[1].YValues)
+	}
+}
+
+func TestStackChartsDifferentDays(t *testing.T) {
+	charts := []chart.TimeSeries{
+		{Name: "a", XValues: testDays(3), YValues: []float64{1, 2, 3}},
+		{Name: "b", XValues: testDays(2), YValues: []float64{1, 2}},
+	}
+	if _, err := stackCharts(&charts, false); err == nil {
+		t.Fatal("expected an error for series with different days")
+	}
+}
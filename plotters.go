@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"github.com/wcharczuk/go-chart"
 	"github.com/wcharczuk/go-chart/drawing"
+	"math"
 	"os"
 	"strings"
 	"time"
 )
 
 // Returns a plot including total cases, healed and dead
-func AndamentoNazionaleCompleto(data *[]NationData, title, filename string) (error, string) {
+func AndamentoNazionaleCompleto(data *[]NationData, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := ""
 
@@ -78,7 +79,7 @@ func AndamentoNazionaleCompleto(data *[]NationData, title, filename string) (err
 
 	annotations := make([]chart.AnnotationSeries, 0)
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldCumulative, FieldCumulative, FieldCumulative}}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
@@ -86,7 +87,7 @@ func AndamentoNazionaleCompleto(data *[]NationData, title, filename string) (err
 }
 
 // Returns a plot with the national data according to the specified fields
-func VociNazione(data *[]NationData, fieldName []string, nationIndex int, title, filename string) (error, string) {
+func VociNazione(data *[]NationData, fieldName []string, nationIndex int, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := ""
 
@@ -97,7 +98,7 @@ func VociNazione(data *[]NationData, fieldName []string, nationIndex int, title,
 	series := make([]chart.TimeSeries, 0)
 	var color drawing.Color
 	var alpha uint8 = 200
-	var fileName string
+	ratios := make(map[int]*ratioParts)
 	for _, v := range fieldName {
 		switch strings.ToLower(v) {
 		case "ricoverati_con_sintomi":
@@ -242,6 +243,27 @@ func VociNazione(data *[]NationData, fieldName []string, nationIndex int, title,
 			xValues, yValues, xNames, err = nationToTimeseries(data, v, nationIndex)
 			color = drawing.Color{175, 232, 169, 255}
 
+			series = append(series, chart.TimeSeries{
+				Name: v,
+				Style: chart.Style{
+					StrokeColor: color,
+					FillColor:   color.WithAlpha(alpha),
+				},
+				YAxis:   0,
+				XValues: *xValues,
+				YValues: *yValues,
+			})
+			break
+		case "nuovi_tamponi", "nuovi_deceduti", "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico":
+			xValues, yValues, xNames, err = nationToTimeseries(data, v, nationIndex)
+			if err != nil {
+				return fmt.Errorf("error while creating %v chart: %v", v, err), ""
+			}
+			color, _ = fieldColor(v)
+			if isPositivityField(v) {
+				ratios[len(series)] = nationPositivitySeries(data, v, nationIndex)
+			}
+
 			series = append(series, chart.TimeSeries{
 				Name: v,
 				Style: chart.Style{
@@ -254,22 +276,43 @@ func VociNazione(data *[]NationData, fieldName []string, nationIndex int, title,
 			})
 			break
 		default:
-			return fmt.Errorf("wrong field name passed"), ""
+			if !isRatioField(v) {
+				return fmt.Errorf("wrong field name passed"), ""
+			}
+			xValues, yValues, xNames, err = nationToTimeseries(data, v, nationIndex)
+			if err != nil {
+				return fmt.Errorf("error while creating %v chart: %v", v, err), ""
+			}
+			color, _ = fieldColor(v)
+
+			series = append(series, chart.TimeSeries{
+				Name: v,
+				Style: chart.Style{
+					StrokeColor: color,
+					FillColor:   color.WithAlpha(alpha),
+				},
+				YAxis:   0,
+				XValues: *xValues,
+				YValues: *yValues,
+			})
 		}
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("no field name passed"), ""
+	}
 
-		annotations := make([]chart.AnnotationSeries, 0)
+	annotations := make([]chart.AnnotationSeries, 0)
 
-		err, fileName = timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
-		if err != nil {
-			return fmt.Errorf("%v", err), ""
-		}
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{ratios: ratios}, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
 	}
 
 	return nil, fileName
 }
 
 // Returns a plot including national total cases
-func TotalePositiviNazione(data *[]NationData, placeAnnotations bool, title, filename string) (error, string) {
+func TotalePositiviNazione(data *[]NationData, placeAnnotations bool, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := "Contagiati"
 
@@ -308,7 +351,7 @@ func TotalePositiviNazione(data *[]NationData, placeAnnotations bool, title, fil
 		annotations = append(annotations, deltaAnnotations(deltas, xTotale, yTotale))
 	}
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldCumulative}}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
@@ -316,7 +359,7 @@ func TotalePositiviNazione(data *[]NationData, placeAnnotations bool, title, fil
 }
 
 // Returns a plot including national total healed
-func TotaleGuaritiNazione(data *[]NationData, placeAnnotations bool, title, filename string) (error, string) {
+func TotaleGuaritiNazione(data *[]NationData, placeAnnotations bool, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := "Guariti"
 
@@ -347,7 +390,7 @@ func TotaleGuaritiNazione(data *[]NationData, placeAnnotations bool, title, file
 	}
 	annotations = append(annotations, deltaAnnotations(deltas, xGuariti, yGuariti))
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldCumulative}}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
@@ -355,7 +398,7 @@ func TotaleGuaritiNazione(data *[]NationData, placeAnnotations bool, title, file
 }
 
 // Returns a plot including national total deaths
-func TotaleDecedutiNazione(data *[]NationData, placeAnnotations bool, title, filename string) (error, string) {
+func TotaleDecedutiNazione(data *[]NationData, placeAnnotations bool, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := "Morti"
 
@@ -388,7 +431,7 @@ func TotaleDecedutiNazione(data *[]NationData, placeAnnotations bool, title, fil
 		annotations = append(annotations, deltaAnnotations(deltas, xDeceduti, yDeceduti))
 	}
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldCumulative}}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
@@ -396,7 +439,7 @@ func TotaleDecedutiNazione(data *[]NationData, placeAnnotations bool, title, fil
 }
 
 // Returns a plot including national current positive cases
-func AttualmentePositiviNazione(data *[]NationData, placeAnnotations bool, title, filename string) (error, string) {
+func AttualmentePositiviNazione(data *[]NationData, placeAnnotations bool, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := "Positivi ancora in vita"
 
@@ -429,7 +472,7 @@ func AttualmentePositiviNazione(data *[]NationData, placeAnnotations bool, title
 		annotations = append(annotations, deltaAnnotations(deltas, xPositivi, yPositivi))
 	}
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldStock}}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
@@ -437,7 +480,7 @@ func AttualmentePositiviNazione(data *[]NationData, placeAnnotations bool, title
 }
 
 // Returns a plot including national new cases
-func NuoviPositiviNazione(data *[]NationData, placeAnnotations bool, title, filename string) (error, string) {
+func NuoviPositiviNazione(data *[]NationData, placeAnnotations bool, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := "Nuovi positivi"
 
@@ -470,7 +513,7 @@ func NuoviPositiviNazione(data *[]NationData, placeAnnotations bool, title, file
 		annotations = append(annotations, deltaAnnotations(deltas, xNuoviPositivi, yNuoviPositivi))
 	}
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldFlow}}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
@@ -478,7 +521,7 @@ func NuoviPositiviNazione(data *[]NationData, placeAnnotations bool, title, file
 }
 
 // Returns a plot with the data of a specified region according to the specified fields
-func VociRegione(data *[]RegionData, fieldName []string, regionIndex int, regionCode int, title, filename string) (error, string) {
+func VociRegione(data *[]RegionData, fieldName []string, regionIndex int, regionCode int, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := ""
 
@@ -489,7 +532,7 @@ func VociRegione(data *[]RegionData, fieldName []string, regionIndex int, region
 	series := make([]chart.TimeSeries, 0)
 	var color drawing.Color
 	var alpha uint8 = 200
-	var fileName string
+	ratios := make(map[int]*ratioParts)
 	for _, v := range fieldName {
 		switch strings.ToLower(v) {
 		case "ricoverati_con_sintomi":
@@ -631,6 +674,27 @@ func VociRegione(data *[]RegionData, fieldName []string, regionIndex int, region
 			xValues, yValues, xNames, err = regionToTimeseries(data, v, regionIndex, regionCode)
 			color = drawing.Color{175, 232, 169, 255}
 
+			series = append(series, chart.TimeSeries{
+				Name: v,
+				Style: chart.Style{
+					StrokeColor: color,
+					FillColor:   color.WithAlpha(alpha),
+				},
+				YAxis:   0,
+				XValues: *xValues,
+				YValues: *yValues,
+			})
+			break
+		case "nuovi_tamponi", "nuovi_deceduti", "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico":
+			xValues, yValues, xNames, err = regionToTimeseries(data, v, regionIndex, regionCode)
+			if err != nil {
+				return fmt.Errorf("error while creating %v chart: %v", v, err), ""
+			}
+			color, _ = fieldColor(v)
+			if isPositivityField(v) {
+				ratios[len(series)] = regionPositivitySeries(data, v, regionIndex, regionCode)
+			}
+
 			series = append(series, chart.TimeSeries{
 				Name: v,
 				Style: chart.Style{
@@ -643,22 +707,43 @@ func VociRegione(data *[]RegionData, fieldName []string, regionIndex int, region
 			})
 			break
 		default:
-			return fmt.Errorf("wrong field name passed"), ""
+			if !isRatioField(v) {
+				return fmt.Errorf("wrong field name passed"), ""
+			}
+			xValues, yValues, xNames, err = regionToTimeseries(data, v, regionIndex, regionCode)
+			if err != nil {
+				return fmt.Errorf("error while creating %v chart: %v", v, err), ""
+			}
+			color, _ = fieldColor(v)
+
+			series = append(series, chart.TimeSeries{
+				Name: v,
+				Style: chart.Style{
+					StrokeColor: color,
+					FillColor:   color.WithAlpha(alpha),
+				},
+				YAxis:   0,
+				XValues: *xValues,
+				YValues: *yValues,
+			})
 		}
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("no field name passed"), ""
+	}
 
-		annotations := make([]chart.AnnotationSeries, 0)
+	annotations := make([]chart.AnnotationSeries, 0)
 
-		err, fileName = timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
-		if err != nil {
-			return fmt.Errorf("%v", err), ""
-		}
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{ratios: ratios, plotArea: regionArea(data, regionCode)}, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
 	}
 
 	return nil, fileName
 }
 
 // Returns a plot with the data of a specified province according to the specified fields
-func VociProvince(data *[]ProvinceData, fieldName []string, provinceIndexes *[]int, title, filename string) (error, string) {
+func VociProvince(data *[]ProvinceData, fieldName []string, provinceIndexes *[]int, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := ""
 
@@ -669,7 +754,6 @@ func VociProvince(data *[]ProvinceData, fieldName []string, provinceIndexes *[]i
 	series := make([]chart.TimeSeries, 0)
 	var color drawing.Color
 	var alpha uint8 = 200
-	var fileName string
 	for _, v := range fieldName {
 		switch strings.ToLower(v) {
 		case "totale_casi":
@@ -704,19 +788,23 @@ func VociProvince(data *[]ProvinceData, fieldName []string, provinceIndexes *[]i
 		default:
 			return fmt.Errorf("wrong field name passed"), ""
 		}
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("no field name passed"), ""
+	}
 
-		annotations := make([]chart.AnnotationSeries, 0)
+	annotations := make([]chart.AnnotationSeries, 0)
 
-		err, fileName = timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
-		if err != nil {
-			return fmt.Errorf("%v", err), ""
-		}
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{plotArea: provinceArea(data, provinceIndexes)}, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
 	}
+
 	return nil, fileName
 }
 
 // Returns total cases for the given province
-func TotalePositiviProvincia(data *[]ProvinceData, provinceIndexes *[]int, title, filename string) (error, string) {
+func TotalePositiviProvincia(data *[]ProvinceData, provinceIndexes *[]int, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := "Contagiati"
 
@@ -748,7 +836,7 @@ func TotalePositiviProvincia(data *[]ProvinceData, provinceIndexes *[]int, title
 
 	annotations := make([]chart.AnnotationSeries, 0)
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldCumulative}, plotArea: provinceArea(data, provinceIndexes)}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
@@ -756,7 +844,7 @@ func TotalePositiviProvincia(data *[]ProvinceData, provinceIndexes *[]int, title
 }
 
 // Returns new cases for the given province
-func NuoviPositiviProvincia(data *[]ProvinceData, provinceIndexes *[]int, placeAnnotations bool, title, filename string) (error, string) {
+func NuoviPositiviProvincia(data *[]ProvinceData, provinceIndexes *[]int, placeAnnotations bool, title, filename string, opts ...ChartOptions) (error, string) {
 	xAxisName := ""
 	yAxisName := "Nuovi positivi"
 
@@ -786,36 +874,1016 @@ func NuoviPositiviProvincia(data *[]ProvinceData, provinceIndexes *[]int, placeA
 		annotations = append(annotations, deltaAnnotations(deltas, xNuoviPositivi, yNuoviPositivi))
 	}
 
-	err, fileName := timeseriesChart(&series, xNames, &annotations, title, filename, xAxisName, yAxisName)
+	err, fileName := timeseriesChart(&series, xNames, &annotations, &plotExtras{kinds: []FieldKind{FieldFlow}, plotArea: provinceArea(data, provinceIndexes)}, title, filename, xAxisName, yAxisName, opts...)
 	if err != nil {
 		return fmt.Errorf("%v", err), ""
 	}
 	return nil, fileName
 }
 
-// Checks if a given plot already exist by title
-func IsGraphExisting(filename string) bool{
-	_, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		return false
-	} else {
-		return true
+// Returns a plot of the weekly incidence of the given region with the background colored by the zone assigned by the rules
+func IncidenzaRegione(data *[]RegionData, regionName string, rules *ZoneRules, year int, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := "Casi in 7 giorni per 100.000 abitanti"
+
+	classifications, err := ClassifyRegion(data, regionName, rules, year)
+	if err != nil {
+		return fmt.Errorf("error while classifying %v: %v", regionName, err), ""
 	}
+	xIncidenza, yIncidenza, err := RegionIncidence(data, regionName, 7, year)
+	if err != nil {
+		return fmt.Errorf("error while creating incidence chart: %v", err), ""
+	}
+	_, populationYear, err := GetRegionPopulation(regionName, year)
+	if err != nil {
+		return fmt.Errorf("error while reading population of %v: %v", regionName, err), ""
+	}
+
+	xNames := make([]chart.GridLine, 0)
+	for _, v := range *xIncidenza {
+		xNames = *dateXAxis(&xNames, v)
+	}
+
+	incidenza := chart.TimeSeries{
+		Name: "Incidenza settimanale",
+		Style: chart.Style{
+			StrokeColor: drawing.Color{R: 18, G: 4, B: 217, A: 255},
+			StrokeWidth: 3,
+		},
+		YAxis:   0,
+		XValues: *xIncidenza,
+		YValues: *yIncidenza,
+	}
+
+	series := make([]chart.TimeSeries, 1)
+	series[0] = incidenza
+
+	// the first days have no complete window, so they are left out of the background too
+	zoneDates := make([]time.Time, 0)
+	zoneLabels := make([]string, 0)
+	for _, v := range *classifications {
+		if !v.Data.Before((*xIncidenza)[0]) {
+			zoneDates = append(zoneDates, v.Data)
+			zoneLabels = append(zoneLabels, "zona "+v.Zona.String())
+		}
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{
+		subtitle:   PopulationSource(populationYear),
+		background: daysToBackgroundSeries(&zoneDates, zoneLabels, zoneColors()),
+		plotArea:   plotArea{regionName: regionName},
+	}
+
+	err, fileName := timeseriesChart(&series, &xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
 }
 
-// Returns a well formatted filename
-func FilenameCreator(plotTitle string) (filename string) {
-	var colorMode string
+// Returns a plot of the 7 and 14 days incidence of the given province
+func IncidenzaProvincia(data *[]ProvinceData, provinceName string, year int, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := "Casi per 100.000 abitanti"
+
+	xIncidenza7, yIncidenza7, err := ProvinceIncidence(data, provinceName, 7, year)
+	if err != nil {
+		return fmt.Errorf("error while creating incidence chart: %v", err), ""
+	}
+	xIncidenza14, yIncidenza14, err := ProvinceIncidence(data, provinceName, 14, year)
+	if err != nil {
+		return fmt.Errorf("error while creating incidence chart: %v", err), ""
+	}
+	_, populationYear, err := GetProvincePopulation(provinceName, year)
+	if err != nil {
+		return fmt.Errorf("error while reading population of %v: %v", provinceName, err), ""
+	}
 
-	lightHour, _ := time.Parse("15:04:05", daySwitch)
-	darkHour, _ := time.Parse("15:04:05", nightSwitch)
-	now := time.Now().Hour()
-	if now >= darkHour.Hour() || now < lightHour.Hour() {
-		colorMode = "dark"
-	} else {
-		colorMode = "light"
+	xNames := make([]chart.GridLine, 0)
+	for _, v := range *xIncidenza7 {
+		xNames = *dateXAxis(&xNames, v)
+	}
+
+	incidenza7 := chart.TimeSeries{
+		Name: "Incidenza a 7 giorni",
+		Style: chart.Style{
+			StrokeColor: drawing.Color{R: 18, G: 4, B: 217, A: 255},
+			StrokeWidth: 3,
+		},
+		YAxis:   0,
+		XValues: *xIncidenza7,
+		YValues: *yIncidenza7,
+	}
+
+	incidenza14 := chart.TimeSeries{
+		Name: "Incidenza a 14 giorni",
+		Style: chart.Style{
+			StrokeColor: drawing.Color{R: 237, G: 164, B: 17, A: 255},
+			StrokeWidth: 3,
+		},
+		YAxis:   0,
+		XValues: *xIncidenza14,
+		YValues: *yIncidenza14,
+	}
+
+	series := make([]chart.TimeSeries, 2)
+	series[0] = incidenza7
+	series[1] = incidenza14
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{subtitle: PopulationSource(populationYear), plotArea: provinceArea(data, GetProvinceIndexesByName(data, provinceName))}
+
+	err, fileName := timeseriesChart(&series, &xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Returns a plot of the national Rt with its credible interval
+func RtNazione(data *[]NationData, config RtConfig, title, filename string, opts ...ChartOptions) (error, string) {
+	estimates, err := NationRt(data, config)
+	if err != nil {
+		return fmt.Errorf("error while estimating Rt: %v", err), ""
+	}
+
+	return rtChart(estimates, config, plotArea{}, title, filename, opts...)
+}
+
+// Returns a plot of the Rt of the given region with its credible interval
+func RtRegione(data *[]RegionData, regionName string, config RtConfig, title, filename string, opts ...ChartOptions) (error, string) {
+	estimates, err := RegionRt(data, regionName, config)
+	if err != nil {
+		return fmt.Errorf("error while estimating Rt: %v", err), ""
+	}
+
+	return rtChart(estimates, config, plotArea{regionName: regionName}, title, filename, opts...)
+}
+
+// Returns a plot of the Rt of the given province with its credible interval
+func RtProvincia(data *[]ProvinceData, provinceName string, config RtConfig, title, filename string, opts ...ChartOptions) (error, string) {
+	estimates, err := ProvinceRt(data, provinceName, config)
+	if err != nil {
+		return fmt.Errorf("error while estimating Rt: %v", err), ""
+	}
+
+	return rtChart(estimates, config, provinceArea(data, GetProvinceIndexesByName(data, provinceName)), title, filename, opts...)
+}
+
+// Creates the plot of Rt estimates with the shaded credible interval and the Rt=1 reference line
+func rtChart(estimates *[]RtEstimate, config RtConfig, area plotArea, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := "Rt"
+
+	if len(*estimates) == 0 {
+		return fmt.Errorf("not enough data to estimate Rt"), ""
+	}
+
+	xValues := make([]time.Time, 0)
+	yValues := make([]float64, 0)
+	lower := make([]float64, 0)
+	upper := make([]float64, 0)
+	xNames := make([]chart.GridLine, 0)
+	for _, v := range *estimates {
+		xValues = append(xValues, v.Data)
+		yValues = append(yValues, v.Rt)
+		lower = append(lower, v.Inferiore)
+		upper = append(upper, v.Superiore)
+		xNames = *dateXAxis(&xNames, v.Data)
+	}
+
+	rt := chart.TimeSeries{
+		Name: "Rt",
+		Style: chart.Style{
+			StrokeColor: drawing.Color{R: 18, G: 4, B: 217, A: 255},
+			StrokeWidth: 3,
+		},
+		YAxis:   0,
+		XValues: xValues,
+		YValues: yValues,
+	}
+
+	series := make([]chart.TimeSeries, 1)
+	series[0] = rt
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{
+		subtitle: fmt.Sprintf("Metodo di Cori et al., finestra di %d giorni", config.Window),
+		overlay:  []chart.Series{referenceLine("Rt = 1", 1, drawing.Color{R: 220, G: 20, B: 20, A: 255}, &xValues)},
+		background: []chart.Series{bandSeries{
+			name:    fmt.Sprintf("Intervallo di credibilità %.0f%%", config.CredibleInterval*100),
+			color:   drawing.Color{R: 18, G: 4, B: 217, A: 60},
+			xValues: xValues,
+			lower:   lower,
+			upper:   upper,
+		}},
+		plotArea: area,
+	}
+
+	err, fileName := timeseriesChart(&series, &xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Returns a plot of the national daily positivity rate along with the daily tests
+func TassoPositivitaNazione(data *[]NationData, title, filename string, opts ...ChartOptions) (error, string) {
+	series := make([]chart.TimeSeries, 0)
+	ratios := make(map[int]*ratioParts)
+	var xNames *[]chart.GridLine
+	for _, fieldName := range []string{"nuovi_tamponi", "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico"} {
+		xValues, yValues, names, err := nationToTimeseries(data, fieldName, 0)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", fieldName, err), ""
+		}
+		if fieldName == "nuovi_tamponi" {
+			xNames = names
+		}
+		if len(*xValues) > 0 {
+			if isPositivityField(fieldName) {
+				ratios[len(series)] = nationPositivitySeries(data, fieldName, 0)
+			}
+			series = append(series, positivityTimeseries(fieldName, xValues, yValues))
+		}
+	}
+
+	return positivityChart(&series, ratios, xNames, plotArea{}, title, filename, opts...)
+}
+
+// Returns a plot of the daily positivity rate of the given region along with the daily tests
+func TassoPositivitaRegione(data *[]RegionData, regionName string, title, filename string, opts ...ChartOptions) (error, string) {
+	regionIndex, err := FindFirstOccurrenceRegion(data, "denominazione_regione", regionName)
+	if err != nil {
+		return fmt.Errorf("error while searching %v: %v", regionName, err), ""
+	}
+
+	series := make([]chart.TimeSeries, 0)
+	ratios := make(map[int]*ratioParts)
+	var xNames *[]chart.GridLine
+	for _, fieldName := range []string{"nuovi_tamponi", "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico"} {
+		xValues, yValues, names, err := regionToTimeseries(data, fieldName, regionIndex, regionIndex%21)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", fieldName, err), ""
+		}
+		if fieldName == "nuovi_tamponi" {
+			xNames = names
+		}
+		if len(*xValues) > 0 {
+			if isPositivityField(fieldName) {
+				ratios[len(series)] = regionPositivitySeries(data, fieldName, regionIndex, regionIndex%21)
+			}
+			series = append(series, positivityTimeseries(fieldName, xValues, yValues))
+		}
+	}
+
+	return positivityChart(&series, ratios, xNames, plotArea{regionName: regionName}, title, filename, opts...)
+}
+
+// Creates the series of a positivity plot, daily tests go on the secondary axis
+func positivityTimeseries(fieldName string, xValues *[]time.Time, yValues *[]float64) chart.TimeSeries {
+	color, _ := fieldColor(fieldName)
+	names := map[string]string{
+		"nuovi_tamponi":               "Tamponi giornalieri",
+		"tasso_positivita":            "Tasso di positività",
+		"tasso_positivita_molecolare": "Tasso di positività test molecolari",
+		"tasso_positivita_antigenico": "Tasso di positività test antigenici",
+	}
+
+	if fieldName == "nuovi_tamponi" {
+		return chart.TimeSeries{
+			Name: names[fieldName],
+			Style: chart.Style{
+				StrokeColor: color,
+				FillColor:   color.WithAlpha(120),
+			},
+			YAxis:   chart.YAxisSecondary,
+			XValues: *xValues,
+			YValues: *yValues,
+		}
+	}
+
+	return chart.TimeSeries{
+		Name: names[fieldName],
+		Style: chart.Style{
+			StrokeColor: color,
+			StrokeWidth: 3,
+		},
+		YAxis:   chart.YAxisPrimary,
+		XValues: *xValues,
+		YValues: *yValues,
+	}
+}
+
+// Creates a plot of positivity rates on the primary axis and daily tests on the secondary one,
+// the rates are aggregated by period from the positives and the tests of their parts
+func positivityChart(series *[]chart.TimeSeries, ratios map[int]*ratioParts, xNames *[]chart.GridLine, area plotArea, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := "Tasso di positività (%)"
+
+	// daily tests are the only series on the secondary axis
+	kinds := make([]FieldKind, 0)
+	for _, v := range *series {
+		if v.YAxis == chart.YAxisSecondary {
+			kinds = append(kinds, FieldFlow)
+		} else {
+			kinds = append(kinds, FieldRate)
+		}
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{
+		subtitle:           "Nuovi positivi sui tamponi del giorno, esclusi i giorni con variazione dei tamponi non positiva",
+		secondaryYAxisName: "Tamponi giornalieri",
+		kinds:              kinds,
+		ratios:             ratios,
+		plotArea:           area,
+	}
+
+	err, fileName := timeseriesChart(series, xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Returns a plot of the daily growth rate of the given national field, marking periods of growth and decline
+func CrescitaNazione(data *[]NationData, fieldName string, title, filename string, opts ...ChartOptions) (error, string) {
+	growth, err := NationGrowth(data, fieldName)
+	if err != nil {
+		return fmt.Errorf("error while calculating growth of %v: %v", fieldName, err), ""
+	}
+
+	return growthChart(growth, fieldName, plotArea{}, title, filename, opts...)
+}
+
+// Returns a plot of the daily growth rate of the given regional field, marking periods of growth and decline
+func CrescitaRegione(data *[]RegionData, fieldName string, regionName string, title, filename string, opts ...ChartOptions) (error, string) {
+	growth, err := RegionGrowth(data, fieldName, regionName)
+	if err != nil {
+		return fmt.Errorf("error while calculating growth of %v: %v", fieldName, err), ""
+	}
+
+	return growthChart(growth, fieldName, plotArea{regionName: regionName}, title, filename, opts...)
+}
+
+// Returns a plot of the daily growth rate of the given provincial field, marking periods of growth and decline
+func CrescitaProvincia(data *[]ProvinceData, fieldName string, provinceName string, title, filename string, opts ...ChartOptions) (error, string) {
+	growth, err := ProvinceGrowth(data, fieldName, provinceName)
+	if err != nil {
+		return fmt.Errorf("error while calculating growth of %v: %v", fieldName, err), ""
+	}
+
+	return growthChart(growth, fieldName, provinceArea(data, GetProvinceIndexesByName(data, provinceName)), title, filename, opts...)
+}
+
+// Creates the plot of the daily growth rate with the periods of growth and decline in the background
+// and the latest doubling or halving time in the subtitle
+func growthChart(growth *[]GrowthRate, fieldName string, area plotArea, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := "Crescita giornaliera (%)"
+
+	xValues := make([]time.Time, 0)
+	yValues := make([]float64, 0)
+	labels := make([]string, 0)
+	xNames := make([]chart.GridLine, 0)
+	var last GrowthRate
+	for _, v := range *growth {
+		if math.IsNaN(v.CrescitaGiornaliera) {
+			continue
+		}
+		xValues = append(xValues, v.Data)
+		yValues = append(yValues, v.CrescitaGiornaliera)
+		xNames = *dateXAxis(&xNames, v.Data)
+		if v.CrescitaGiornaliera > 0 {
+			labels = append(labels, "crescita")
+		} else if v.CrescitaGiornaliera < 0 {
+			labels = append(labels, "decrescita")
+		} else {
+			labels = append(labels, "")
+		}
+		last = v
+	}
+	if len(xValues) < 2 {
+		return fmt.Errorf("error while creating growth chart: not enough data"), ""
+	}
+
+	var subtitle string
+	if !math.IsNaN(last.TempoRaddoppio) {
+		subtitle = fmt.Sprintf("Tempo di raddoppio al %v: %.1f giorni", last.Data.Format("02/01/2006"), last.TempoRaddoppio)
+	} else if !math.IsNaN(last.TempoDimezzamento) {
+		subtitle = fmt.Sprintf("Tempo di dimezzamento al %v: %.1f giorni", last.Data.Format("02/01/2006"), last.TempoDimezzamento)
 	}
 
-	filename = plotTitle + "-" + colorMode + ".png"
+	series := make([]chart.TimeSeries, 1)
+	series[0] = chart.TimeSeries{
+		Name: fieldName,
+		Style: chart.Style{
+			StrokeColor: drawing.Color{R: 18, G: 4, B: 217, A: 255},
+			StrokeWidth: 3,
+		},
+		YAxis:   0,
+		XValues: xValues,
+		YValues: yValues,
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{
+		subtitle: subtitle,
+		background: daysToBackgroundSeries(&xValues, labels, map[string]drawing.Color{
+			"crescita":   {R: 220, G: 20, B: 20, A: 60},
+			"decrescita": {R: 40, G: 160, B: 60, A: 60},
+		}),
+		overlay:  []chart.Series{referenceLine("", 0, drawing.Color{R: 120, G: 120, B: 120, A: 255}, &xValues)},
+		kinds:    []FieldKind{FieldRate},
+		plotArea: area,
+	}
+
+	err, fileName := timeseriesChart(&series, &xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Returns a horizontal bar chart of the regions ranked according to the options, highlighting the given one if not empty.
+// The Per100k chart option ranks by the values per 100.000 inhabitants too
+func ClassificaRegioni(data *[]RegionData, options RankOptions, highlight string, title, filename string, opts ...ChartOptions) (error, string) {
+	options = perCapitaRanking(options, opts)
+	entries, err := RankRegions(data, options)
+	if err != nil {
+		return fmt.Errorf("error while ranking regions: %v", err), ""
+	}
+
+	return rankingChart(entries, rankingColor(options.Field), highlight, rankingSubtitle(options), title, filename, opts...)
+}
+
+// Returns a horizontal bar chart of the provinces ranked according to the options, highlighting the given one if not empty.
+// The Per100k chart option ranks by the values per 100.000 inhabitants too
+func ClassificaProvince(data *[]ProvinceData, options RankOptions, highlight string, title, filename string, opts ...ChartOptions) (error, string) {
+	options = perCapitaRanking(options, opts)
+	entries, err := RankProvinces(data, options)
+	if err != nil {
+		return fmt.Errorf("error while ranking provinces: %v", err), ""
+	}
+
+	return rankingChart(entries, rankingColor(options.Field), highlight, rankingSubtitle(options), title, filename, opts...)
+}
+
+// Returns the ranking options per 100.000 inhabitants when the chart options ask for it and the field is not a rate
+func perCapitaRanking(options RankOptions, opts []ChartOptions) RankOptions {
+	chartOptions := getChartOptions(opts)
+	if !chartOptions.Per100k || options.Per100k {
+		return options
+	}
+	if kind, err := GetFieldKind(options.Field); err != nil || kind == FieldRate {
+		return options
+	}
+
+	options.Per100k = true
+	options.PopulationYear = chartOptions.PopulationYear
+	return options
+}
+
+// Returns the color of the bars of a ranking by the given field
+func rankingColor(fieldName string) drawing.Color {
+	color, err := fieldColor(fieldName)
+	if err != nil {
+		return drawing.Color{R: 18, G: 4, B: 217, A: 255}
+	}
+	return color
+}
+
+// Describes what a ranking is based on
+func rankingSubtitle(options RankOptions) string {
+	subtitle := strings.ToLower(options.Field)
+	if options.Per100k {
+		subtitle += " per 100.000 abitanti"
+	}
+
+	day := "all'ultimo giorno disponibile"
+	if !options.Date.IsZero() {
+		day = "al " + options.Date.Format("02/01/2006")
+	}
+	if options.Days > 0 {
+		return fmt.Sprintf("%v, %d giorni fino %v", subtitle, options.Days, day)
+	}
+	return fmt.Sprintf("%v %v", subtitle, day)
+}
+
+// Returns a plot of the national occupancy of intensive care and ordinary ward beds with the critical thresholds
+func OccupazioneNazione(data *[]NationData, title, filename string, opts ...ChartOptions) (error, string) {
+	occupancies, err := NationOccupancy(data)
+	if err != nil {
+		return fmt.Errorf("error while calculating occupancy: %v", err), ""
+	}
+	if len(*occupancies) == 0 {
+		return fmt.Errorf("error while calculating occupancy: not enough data"), ""
+	}
+	capacity, err := GetNationBedCapacity((*occupancies)[len(*occupancies)-1].Data)
+	if err != nil {
+		return fmt.Errorf("error while reading bed capacity: %v", err), ""
+	}
+
+	return occupancyChart(occupancies, capacity, plotArea{}, title, filename, opts...)
+}
+
+// Returns a plot of the occupancy of intensive care and ordinary ward beds of the given region with the critical thresholds
+func OccupazioneRegione(data *[]RegionData, regionName string, title, filename string, opts ...ChartOptions) (error, string) {
+	occupancies, err := RegionOccupancy(data, regionName)
+	if err != nil {
+		return fmt.Errorf("error while calculating occupancy of %v: %v", regionName, err), ""
+	}
+	if len(*occupancies) == 0 {
+		return fmt.Errorf("error while calculating occupancy of %v: not enough data", regionName), ""
+	}
+	capacity, err := GetRegionBedCapacity(regionName, (*occupancies)[len(*occupancies)-1].Data)
+	if err != nil {
+		return fmt.Errorf("error while reading bed capacity of %v: %v", regionName, err), ""
+	}
+
+	return occupancyChart(occupancies, capacity, plotArea{regionName: regionName}, title, filename, opts...)
+}
+
+// Creates the plot of bed occupancy with the threshold lines and the latest capacity in the subtitle
+func occupancyChart(occupancies *[]Occupancy, capacity *BedCapacity, area plotArea, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := "Posti letto occupati (%)"
+
+	xValues := make([]time.Time, 0)
+	icu := make([]float64, 0)
+	ward := make([]float64, 0)
+	xNames := make([]chart.GridLine, 0)
+	for _, v := range *occupancies {
+		if math.IsNaN(v.Terapia_intensiva) || math.IsNaN(v.Area_medica) {
+			continue
+		}
+		xValues = append(xValues, v.Data)
+		icu = append(icu, v.Terapia_intensiva)
+		ward = append(ward, v.Area_medica)
+		xNames = *dateXAxis(&xNames, v.Data)
+	}
+	if len(xValues) < 2 {
+		return fmt.Errorf("error while creating occupancy chart: not enough data"), ""
+	}
+
+	icuColor, _ := fieldColor("terapia_intensiva")
+	wardColor, _ := fieldColor("ricoverati_con_sintomi")
+	series := make([]chart.TimeSeries, 2)
+	series[0] = chart.TimeSeries{
+		Name: "Terapia intensiva",
+		Style: chart.Style{
+			StrokeColor: icuColor,
+			StrokeWidth: 3,
+		},
+		YAxis:   0,
+		XValues: xValues,
+		YValues: icu,
+	}
+	series[1] = chart.TimeSeries{
+		Name: "Area medica",
+		Style: chart.Style{
+			StrokeColor: wardColor,
+			StrokeWidth: 3,
+		},
+		YAxis:   0,
+		XValues: xValues,
+		YValues: ward,
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{
+		subtitle: fmt.Sprintf("Posti letto dal %v: %d in terapia intensiva, %d in area medica (fonte: %v)",
+			capacity.Data.Format("02/01/2006"), capacity.Posti_terapia_intensiva, capacity.Posti_area_medica, capacity.Fonte),
+		overlay: []chart.Series{
+			referenceLine(fmt.Sprintf("Soglia terapia intensiva %.0f%%", SogliaTerapiaIntensiva), SogliaTerapiaIntensiva, icuColor, &xValues),
+			referenceLine(fmt.Sprintf("Soglia area medica %.0f%%", SogliaAreaMedica), SogliaAreaMedica, wardColor, &xValues),
+		},
+		kinds:    []FieldKind{FieldRate, FieldRate},
+		plotArea: area,
+	}
+
+	err, fileName := timeseriesChart(&series, &xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Returns a plot comparing a ratio field among the given regions, all of them when no region is given
+func ConfrontoRapportoRegioni(data *[]RegionData, fieldName string, regions []string, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := ratioName(fieldName)
+
+	if !isRatioField(fieldName) {
+		return fmt.Errorf("wrong field name passed"), ""
+	}
+	if len(regions) == 0 {
+		if len(*data) < 21 {
+			return fmt.Errorf("not enough regional data"), ""
+		}
+		firstDay := (*data)[:21]
+		regions = GetRegionsNamesList(&firstDay)
+	}
+
+	palette := getChartOptions(opts).theme().Palette
+	var xNames *[]chart.GridLine
+	series := make([]chart.TimeSeries, 0)
+	kinds := make([]FieldKind, 0)
+	for i, regionName := range regions {
+		regionIndex, err := FindFirstOccurrenceRegion(data, "denominazione_regione", regionName)
+		if err != nil {
+			return fmt.Errorf("error while searching %v: %v", regionName, err), ""
+		}
+		xValues, yValues, names, err := regionToTimeseries(data, fieldName, regionIndex, regionIndex%21)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart of %v: %v", fieldName, regionName, err), ""
+		}
+		if len(*xValues) == 0 {
+			continue
+		}
+		if xNames == nil || len(*names) > len(*xNames) {
+			xNames = names
+		}
+
+		series = append(series, chart.TimeSeries{
+			Name:    regionName,
+			Style:   comparisonStyle(i, palette),
+			YAxis:   0,
+			XValues: *xValues,
+			YValues: *yValues,
+		})
+		kinds = append(kinds, FieldRate)
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("error while creating %v chart: no data", fieldName), ""
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{kinds: kinds}
+
+	err, fileName := timeseriesChart(&series, xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Returns a plot of the national following field with the leading one shifted by the lag estimated
+// through cross-correlation between minLag and maxLag days
+func RitardoNazione(data *[]NationData, leadField, followField string, minLag, maxLag int, title, filename string, opts ...ChartOptions) (error, string) {
+	leadDates, leadValues, err := NationSeries(data, leadField)
+	if err != nil {
+		return fmt.Errorf("error while creating %v chart: %v", leadField, err), ""
+	}
+	followDates, followValues, err := NationSeries(data, followField)
+	if err != nil {
+		return fmt.Errorf("error while creating %v chart: %v", followField, err), ""
+	}
+
+	return lagChart(leadDates, leadValues, leadField, followDates, followValues, followField, minLag, maxLag, plotArea{}, title, filename, opts...)
+}
+
+// Returns a plot of the following field of the given region with the leading one shifted by the lag estimated
+// through cross-correlation between minLag and maxLag days
+func RitardoRegione(data *[]RegionData, leadField, followField string, regionName string, minLag, maxLag int, title, filename string, opts ...ChartOptions) (error, string) {
+	leadDates, leadValues, err := RegionSeries(data, leadField, regionName)
+	if err != nil {
+		return fmt.Errorf("error while creating %v chart: %v", leadField, err), ""
+	}
+	followDates, followValues, err := RegionSeries(data, followField, regionName)
+	if err != nil {
+		return fmt.Errorf("error while creating %v chart: %v", followField, err), ""
+	}
+
+	return lagChart(leadDates, leadValues, leadField, followDates, followValues, followField, minLag, maxLag, plotArea{regionName: regionName}, title, filename, opts...)
+}
+
+// Creates the plot of the weekly averages of the following field on the primary axis
+// and of the leading one, shifted forward by the estimated lag, on the secondary axis
+func lagChart(leadDates *[]time.Time, leadValues *[]float64, leadField string, followDates *[]time.Time, followValues *[]float64, followField string, minLag, maxLag int, area plotArea, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := followField
+
+	correlation, err := crossCorrelateFields(leadDates, leadValues, leadField, followDates, followValues, followField, minLag, maxLag)
+	if err != nil {
+		return fmt.Errorf("error while correlating %v and %v: %v", leadField, followField, err), ""
+	}
+
+	series := make([]chart.TimeSeries, 0)
+	kinds := make([]FieldKind, 0)
+	xNames := make([]chart.GridLine, 0)
+	for i, v := range []struct {
+		field  string
+		dates  *[]time.Time
+		values *[]float64
+		shift  int
+	}{
+		{followField, followDates, followValues, 0},
+		{leadField, leadDates, leadValues, correlation.MigliorRitardo},
+	} {
+		kind, err := GetFieldKind(v.field)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", v.field, err), ""
+		}
+		prepared, err := correlationSeries(v.values, kind)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", v.field, err), ""
+		}
+		// cumulative fields are plotted as their daily amounts
+		if kind == FieldCumulative {
+			kind = FieldFlow
+		}
+
+		xValues := make([]time.Time, 0)
+		yValues := make([]float64, 0)
+		for j, date := range *v.dates {
+			if math.IsNaN((*prepared)[j]) {
+				continue
+			}
+			shifted := date.AddDate(0, 0, v.shift)
+			xValues = append(xValues, shifted)
+			yValues = append(yValues, (*prepared)[j])
+			// the shifted series may go past the days of the following one
+			if i == 0 || (len(xNames) > 0 && chart.TimeToFloat64(shifted) > xNames[len(xNames)-1].Value) {
+				xNames = *dateXAxis(&xNames, shifted)
+			}
+		}
+
+		color, err := fieldColor(v.field)
+		if err != nil {
+			color = chart.ColorBlue
+		}
+		timeseries := chart.TimeSeries{
+			Name: v.field,
+			Style: chart.Style{
+				StrokeColor: color,
+				StrokeWidth: 3,
+			},
+			YAxis:   chart.YAxisPrimary,
+			XValues: xValues,
+			YValues: yValues,
+		}
+		// the leading series goes on the secondary axis, as the two fields usually have different scales
+		if i == 1 {
+			timeseries.Name = fmt.Sprintf("%v spostato di %d giorni", v.field, v.shift)
+			timeseries.Style.StrokeDashArray = []float64{8, 4}
+			timeseries.YAxis = chart.YAxisSecondary
+		}
+		series = append(series, timeseries)
+		kinds = append(kinds, kind)
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{
+		subtitle: fmt.Sprintf("Ritardo stimato di %d giorni con correlazione %.2f tra le medie settimanali",
+			correlation.MigliorRitardo, correlation.MigliorCorrelazione),
+		secondaryYAxisName: leadField,
+		kinds:              kinds,
+		plotArea:           area,
+	}
+
+	err, fileName := timeseriesChart(&series, &xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Returns a plot comparing the national field in the versions fetched on the given times,
+// the last five stored versions when no time is given
+func RevisioniNazione(store *RevisionStore, fieldName string, fetchTimes []time.Time, title, filename string, opts ...ChartOptions) (error, string) {
+	fetchTimes, err := revisionTimes(store, DatasetNazione, fetchTimes)
+	if err != nil {
+		return fmt.Errorf("error while listing revisions: %v", err), ""
+	}
+
+	palette := getChartOptions(opts).theme().Palette
+	series := make([]chart.TimeSeries, 0)
+	for _, v := range fetchTimes {
+		data, fetched, err := store.NationAsOf(v)
+		if err != nil {
+			return fmt.Errorf("error while loading revision: %v", err), ""
+		}
+		dates, values, err := NationSeries(data, fieldName)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", fieldName, err), ""
+		}
+		series = append(series, revisionTimeseries(len(series), palette, fetched, dates, values))
+	}
+
+	return revisionsChart(&series, fieldName, plotArea{}, title, filename, opts...)
+}
+
+// Returns a plot comparing the regional field of the given region in the versions fetched on the given times,
+// the last five stored versions when no time is given
+func RevisioniRegione(store *RevisionStore, fieldName string, regionName string, fetchTimes []time.Time, title, filename string, opts ...ChartOptions) (error, string) {
+	fetchTimes, err := revisionTimes(store, DatasetRegioni, fetchTimes)
+	if err != nil {
+		return fmt.Errorf("error while listing revisions: %v", err), ""
+	}
+
+	palette := getChartOptions(opts).theme().Palette
+	series := make([]chart.TimeSeries, 0)
+	for _, v := range fetchTimes {
+		data, fetched, err := store.RegionsAsOf(v)
+		if err != nil {
+			return fmt.Errorf("error while loading revision: %v", err), ""
+		}
+		dates, values, err := RegionSeries(data, fieldName, regionName)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", fieldName, err), ""
+		}
+		series = append(series, revisionTimeseries(len(series), palette, fetched, dates, values))
+	}
+
+	return revisionsChart(&series, fieldName, plotArea{regionName: regionName}, title, filename, opts...)
+}
+
+// Returns a plot comparing the provincial field of the given province in the versions fetched on the given times,
+// the last five stored versions when no time is given
+func RevisioniProvincia(store *RevisionStore, fieldName string, provinceName string, fetchTimes []time.Time, title, filename string, opts ...ChartOptions) (error, string) {
+	fetchTimes, err := revisionTimes(store, DatasetProvince, fetchTimes)
+	if err != nil {
+		return fmt.Errorf("error while listing revisions: %v", err), ""
+	}
+
+	palette := getChartOptions(opts).theme().Palette
+	series := make([]chart.TimeSeries, 0)
+	var area plotArea
+	for _, v := range fetchTimes {
+		data, fetched, err := store.ProvincesAsOf(v)
+		if err != nil {
+			return fmt.Errorf("error while loading revision: %v", err), ""
+		}
+		dates, values, err := ProvinceSeries(data, fieldName, provinceName)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", fieldName, err), ""
+		}
+		area = provinceArea(data, GetProvinceIndexesByName(data, provinceName))
+		series = append(series, revisionTimeseries(len(series), palette, fetched, dates, values))
+	}
+
+	return revisionsChart(&series, fieldName, area, title, filename, opts...)
+}
+
+// Returns the given fetch times, or the ones of the last five stored versions of the dataset when none is given
+func revisionTimes(store *RevisionStore, dataset string, fetchTimes []time.Time) ([]time.Time, error) {
+	if len(fetchTimes) > 0 {
+		return fetchTimes, nil
+	}
+
+	stored, err := store.Revisions(dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		return nil, fmt.Errorf("no revision of %v stored", dataset)
+	}
+	if len(stored) > 5 {
+		stored = stored[len(stored)-5:]
+	}
+	return stored, nil
+}
+
+// Creates the line of a version, the i-th of the plot
+func revisionTimeseries(i int, palette []drawing.Color, fetched time.Time, dates *[]time.Time, values *[]float64) chart.TimeSeries {
+	return chart.TimeSeries{
+		Name:    "Versione del " + fetched.Format("02/01/2006 15:04"),
+		Style:   comparisonStyle(i, palette),
+		YAxis:   0,
+		XValues: *dates,
+		YValues: *values,
+	}
+}
+
+// Creates the plot comparing the versions of a field
+func revisionsChart(series *[]chart.TimeSeries, fieldName string, area plotArea, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := fieldName
+
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return fmt.Errorf("error while creating %v chart: %v", fieldName, err), ""
+	}
+
+	xNames := make([]chart.GridLine, 0)
+	kinds := make([]FieldKind, 0)
+	for _, v := range *series {
+		if len(v.XValues) > len(xNames) {
+			xNames = make([]chart.GridLine, 0)
+			for _, date := range v.XValues {
+				xNames = *dateXAxis(&xNames, date)
+			}
+		}
+		kinds = append(kinds, kind)
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{kinds: kinds, plotArea: area}
+
+	err, fileName := timeseriesChart(series, &xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Fields making up the current positives, from the bottom to the top of composition plots
+var positiviComposition = []string{"terapia_intensiva", "ricoverati_con_sintomi", "isolamento_domiciliare"}
+
+// Returns a plot of the national current positives split into intensive care, hospitalised with symptoms
+// and home isolation, stacked on each other, in percent of the current positives when normalized.
+// Forecast, Smoothing, Bars and Waves are not available for these plots
+func ComposizionePositiviNazione(data *[]NationData, normalized bool, title, filename string, opts ...ChartOptions) (error, string) {
+	var xNames *[]chart.GridLine
+	series := make([]chart.TimeSeries, 0)
+	for _, v := range positiviComposition {
+		xValues, yValues, names, err := nationToTimeseries(data, v, 0)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart: %v", v, err), ""
+		}
+		xNames = names
+		series = append(series, compositionTimeseries(v, xValues, yValues))
+	}
+
+	return compositionChart(&series, xNames, normalized, plotArea{}, title, filename, opts...)
+}
+
+// Returns a plot of the current positives of the given region split into intensive care, hospitalised with symptoms
+// and home isolation, stacked on each other, in percent of the current positives when normalized.
+// Forecast, Smoothing, Bars and Waves are not available for these plots
+func ComposizionePositiviRegione(data *[]RegionData, regionName string, normalized bool, title, filename string, opts ...ChartOptions) (error, string) {
+	regionIndex, err := FindFirstOccurrenceRegion(data, "denominazione_regione", regionName)
+	if err != nil {
+		return fmt.Errorf("error while searching %v: %v", regionName, err), ""
+	}
+
+	var xNames *[]chart.GridLine
+	series := make([]chart.TimeSeries, 0)
+	for _, v := range positiviComposition {
+		xValues, yValues, names, err := regionToTimeseries(data, v, regionIndex, regionIndex%21)
+		if err != nil {
+			return fmt.Errorf("error while creating %v chart of %v: %v", v, regionName, err), ""
+		}
+		xNames = names
+		series = append(series, compositionTimeseries(v, xValues, yValues))
+	}
+
+	return compositionChart(&series, xNames, normalized, regionArea(data, regionIndex), title, filename, opts...)
+}
+
+// Creates the series of a field of the composition plots
+func compositionTimeseries(fieldName string, xValues *[]time.Time, yValues *[]float64) chart.TimeSeries {
+	color, _ := fieldColor(fieldName)
+
+	return chart.TimeSeries{
+		Name:    fieldName,
+		Style:   chart.Style{StrokeColor: color},
+		YAxis:   0,
+		XValues: *xValues,
+		YValues: *yValues,
+	}
+}
+
+// Creates the plot stacking the fields making up the current positives
+func compositionChart(series *[]chart.TimeSeries, xNames *[]chart.GridLine, normalized bool, area plotArea, title, filename string, opts ...ChartOptions) (error, string) {
+	xAxisName := ""
+	yAxisName := "Attualmente positivi"
+	if normalized {
+		yAxisName = "Attualmente positivi (%)"
+	}
+
+	kinds := make([]FieldKind, len(*series))
+	for i := range kinds {
+		kinds[i] = FieldStock
+	}
+
+	annotations := make([]chart.AnnotationSeries, 0)
+	extras := &plotExtras{kinds: kinds, stacked: true, normalized: normalized, plotArea: area}
+
+	err, fileName := timeseriesChart(series, xNames, &annotations, extras, title, filename, xAxisName, yAxisName, opts...)
+	if err != nil {
+		return fmt.Errorf("%v", err), ""
+	}
+	return nil, fileName
+}
+
+// Checks if a given plot already exist by title
+func IsGraphExisting(filename string) bool{
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	} else {
+		return true
+	}
+}
+
+// Returns a well formatted filename, the one the plot functions save to with the given options when passed no filename
+func FilenameCreator(plotTitle string, opts ...ChartOptions) (filename string) {
+	options := getChartOptions(opts)
+
+	filename = plotTitle + "-" + options.theme().Name + options.Format.Extension()
 	return
 }
@@ -0,0 +1,194 @@
+package covidgraphs
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed data/popolazione_istat.csv
+var populationCSV string
+
+// Population data struct containing the resident population of an area on January 1st of the given year.
+// Nation rows have no region code, region rows have no province code
+type PopulationData struct {
+	Anno                    int
+	Codice_regione          int
+	Denominazione_regione   string
+	Codice_provincia        int
+	Sigla_provincia         string
+	Denominazione_provincia string
+	Popolazione             int
+}
+
+var populationMutex sync.Mutex
+var populationTable *[]PopulationData
+
+// Returns the population table, parsing the embedded ISTAT data on first use
+func GetPopulation() (*[]PopulationData, error) {
+	populationMutex.Lock()
+	defer populationMutex.Unlock()
+
+	if populationTable == nil {
+		table, err := parsePopulation(strings.NewReader(populationCSV))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing embedded population data: %v", err)
+		}
+		populationTable = table
+	}
+
+	return populationTable, nil
+}
+
+// Replaces the population table with the one read from the given CSV, in the same format as the embedded one
+func LoadPopulation(r io.Reader) error {
+	table, err := parsePopulation(r)
+	if err != nil {
+		return err
+	}
+
+	populationMutex.Lock()
+	populationTable = table
+	populationMutex.Unlock()
+	return nil
+}
+
+// Parses a population CSV
+func parsePopulation(r io.Reader) (*[]PopulationData, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 7
+
+	table := make([]PopulationData, 0)
+	header := true
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error while parsing population: %v", err)
+		}
+		if header {
+			header = false
+			continue
+		}
+
+		var row PopulationData
+		row.Anno, err = strconv.Atoi(line[0])
+		if err != nil {
+			return nil, fmt.Errorf("wrong year in population data: %v", err)
+		}
+		row.Codice_regione, _ = strconv.Atoi(line[1])
+		row.Denominazione_regione = line[2]
+		row.Codice_provincia, _ = strconv.Atoi(line[3])
+		row.Sigla_provincia = line[4]
+		row.Denominazione_provincia = line[5]
+		row.Popolazione, err = strconv.Atoi(line[6])
+		if err != nil {
+			return nil, fmt.Errorf("wrong population value: %v", err)
+		}
+		table = append(table, row)
+	}
+
+	return &table, nil
+}
+
+// Returns the national population for the given year and the year the figure refers to
+func GetNationPopulation(year int) (int, int, error) {
+	return findPopulation(year, func(p PopulationData) bool {
+		return p.Codice_regione == 0 && p.Codice_provincia == 0
+	})
+}
+
+// Returns the population of the given region for the given year and the year the figure refers to
+func GetRegionPopulation(regionName string, year int) (int, int, error) {
+	return findPopulation(year, func(p PopulationData) bool {
+		return p.Codice_regione != 0 && p.Codice_provincia == 0 && sameAreaName(p.Denominazione_regione, regionName)
+	})
+}
+
+// Returns the population of the given province, by name or abbreviation, for the given year and the year the figure refers to
+func GetProvincePopulation(provinceName string, year int) (int, int, error) {
+	return findPopulation(year, func(p PopulationData) bool {
+		return p.Codice_provincia != 0 &&
+			(sameAreaName(p.Denominazione_provincia, provinceName) || strings.EqualFold(p.Sigla_provincia, provinceName))
+	})
+}
+
+// Finds the population of the rows selected by match, using the latest year not after the requested one.
+// If there is no such year the earliest available is used, a zero year selects the latest available
+func findPopulation(year int, match func(p PopulationData) bool) (int, int, error) {
+	table, err := GetPopulation()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	byYear := make(map[int]int)
+	years := make([]int, 0)
+	for _, p := range *table {
+		if match(p) {
+			if _, ok := byYear[p.Anno]; !ok {
+				years = append(years, p.Anno)
+			}
+			byYear[p.Anno] = p.Popolazione
+		}
+	}
+	if len(years) == 0 {
+		return 0, 0, fmt.Errorf("population not found")
+	}
+	sort.Ints(years)
+
+	chosen := years[0]
+	for _, y := range years {
+		if year == 0 || y <= year {
+			chosen = y
+		}
+	}
+
+	return byYear[chosen], chosen, nil
+}
+
+// Returns the population of the area of a plot for the given year and the year the figure refers to
+func areaPopulation(area plotArea, year int) (int, int, error) {
+	if area.provinceName != "" {
+		population, populationYear, err := GetProvincePopulation(area.provinceName, year)
+		if err != nil {
+			return 0, 0, fmt.Errorf("province %v: %v", area.provinceName, err)
+		}
+		return population, populationYear, nil
+	}
+	if area.regionName != "" {
+		population, populationYear, err := GetRegionPopulation(area.regionName, year)
+		if err != nil {
+			return 0, 0, fmt.Errorf("region %v: %v", area.regionName, err)
+		}
+		return population, populationYear, nil
+	}
+
+	return GetNationPopulation(year)
+}
+
+// Compares two region or province names ignoring case and dashes
+func sameAreaName(a, b string) bool {
+	return strings.Replace(strings.ToLower(a), "-", " ", -1) == strings.Replace(strings.ToLower(b), "-", " ", -1)
+}
+
+// Returns the description of the population source to be shown on plots
+func PopulationSource(year int) string {
+	return fmt.Sprintf("Popolazione residente ISTAT al 1° gennaio %d", year)
+}
+
+// Converts values to values per 100.000 inhabitants
+func per100k(values *[]float64, population int) *[]float64 {
+	scaled := make([]float64, len(*values))
+	for i, v := range *values {
+		scaled[i] = v * 100000 / float64(population)
+	}
+
+	return &scaled
+}
@@ -0,0 +1,135 @@
+package covidgraphs
+
+import (
+	"strings"
+)
+
+// Returns the positives and the tests performed between two days. Their positivity rate is not defined
+// when the tests delta is not positive or when there are more positives than tests
+func positivityParts(positives float64, previousTests, tests int) (float64, float64, bool) {
+	testsDelta := tests - previousTests
+	if testsDelta <= 0 || positives < 0 || positives > float64(testsDelta) {
+		return 0, 0, false
+	}
+
+	return positives, float64(testsDelta), true
+}
+
+// Positives and tests of each day of a positivity rate series, the rate of a period is the one of their sums
+type ratioParts struct {
+	numerators   []float64
+	denominators []float64
+}
+
+// Calculates the positivity rate of the given day of the nation data, for all tests or only for molecular or antigen ones
+func nationPositivityRate(data *[]NationData, i int, fieldName string) (float64, bool) {
+	positives, tests, ok := nationPositivity(data, i, fieldName)
+	if !ok {
+		return 0, false
+	}
+
+	return positives * 100 / tests, true
+}
+
+// Returns the positives and the tests of the given day of the nation data, when their positivity rate is defined
+func nationPositivity(data *[]NationData, i int, fieldName string) (float64, float64, bool) {
+	if i < 1 {
+		return 0, 0, false
+	}
+
+	current := (*data)[i]
+	previous := (*data)[i-1]
+	switch strings.ToLower(fieldName) {
+	case "tasso_positivita_molecolare":
+		// split data is available only from January 2021
+		if previous.Tamponi_test_molecolare == 0 {
+			return 0, 0, false
+		}
+		return positivityParts(float64(current.Totale_positivi_test_molecolare-previous.Totale_positivi_test_molecolare),
+			previous.Tamponi_test_molecolare, current.Tamponi_test_molecolare)
+	case "tasso_positivita_antigenico":
+		if previous.Tamponi_test_antigenico_rapido == 0 {
+			return 0, 0, false
+		}
+		return positivityParts(float64(current.Totale_positivi_test_antigenico_rapido-previous.Totale_positivi_test_antigenico_rapido),
+			previous.Tamponi_test_antigenico_rapido, current.Tamponi_test_antigenico_rapido)
+	default:
+		return positivityParts(float64(current.Nuovi_positivi), previous.Tamponi, current.Tamponi)
+	}
+}
+
+// Calculates the positivity rate of the given day of the regional data, for all tests or only for molecular or antigen ones
+func regionPositivityRate(data *[]RegionData, i int, fieldName string) (float64, bool) {
+	positives, tests, ok := regionPositivity(data, i, fieldName)
+	if !ok {
+		return 0, false
+	}
+
+	return positives * 100 / tests, true
+}
+
+// Returns the positives and the tests of the given day of the regional data, when their positivity rate is defined
+func regionPositivity(data *[]RegionData, i int, fieldName string) (float64, float64, bool) {
+	if i < 21 {
+		return 0, 0, false
+	}
+
+	current := (*data)[i]
+	previous := (*data)[i-21]
+	switch strings.ToLower(fieldName) {
+	case "tasso_positivita_molecolare":
+		if previous.Tamponi_test_molecolare == 0 {
+			return 0, 0, false
+		}
+		return positivityParts(float64(current.Totale_positivi_test_molecolare-previous.Totale_positivi_test_molecolare),
+			previous.Tamponi_test_molecolare, current.Tamponi_test_molecolare)
+	case "tasso_positivita_antigenico":
+		if previous.Tamponi_test_antigenico_rapido == 0 {
+			return 0, 0, false
+		}
+		return positivityParts(float64(current.Totale_positivi_test_antigenico_rapido-previous.Totale_positivi_test_antigenico_rapido),
+			previous.Tamponi_test_antigenico_rapido, current.Tamponi_test_antigenico_rapido)
+	default:
+		return positivityParts(float64(current.Nuovi_positivi), previous.Tamponi, current.Tamponi)
+	}
+}
+
+// Returns whether the field is one of the positivity rates
+func isPositivityField(fieldName string) bool {
+	switch strings.ToLower(fieldName) {
+	case "tasso_positivita", "tasso_positivita_molecolare", "tasso_positivita_antigenico":
+		return true
+	}
+	return false
+}
+
+// Returns the positives and the tests of the days of the given national positivity rate, starting from the given index
+func nationPositivitySeries(data *[]NationData, fieldName string, index int) *ratioParts {
+	parts := &ratioParts{numerators: make([]float64, 0), denominators: make([]float64, 0)}
+	for i := index; i < len(*data); i++ {
+		positives, tests, ok := nationPositivity(data, i, fieldName)
+		if !ok {
+			continue
+		}
+		parts.numerators = append(parts.numerators, positives)
+		parts.denominators = append(parts.denominators, tests)
+	}
+
+	return parts
+}
+
+// Returns the positives and the tests of the days of the given regional positivity rate, for the region
+// with the given code starting from the given index
+func regionPositivitySeries(data *[]RegionData, fieldName string, index int, startRegionCodeIndex int) *ratioParts {
+	parts := &ratioParts{numerators: make([]float64, 0), denominators: make([]float64, 0)}
+	for i := regionFirstIndex(index, startRegionCodeIndex); i < len(*data); i += 21 {
+		positives, tests, ok := regionPositivity(data, i, fieldName)
+		if !ok {
+			continue
+		}
+		parts.numerators = append(parts.numerators, positives)
+		parts.denominators = append(parts.denominators, tests)
+	}
+
+	return parts
+}
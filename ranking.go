@@ -0,0 +1,217 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Parameters of a ranking.
+// Field is any field accepted by the series builders, optionally per 100.000 inhabitants of PopulationYear.
+// The value of each area is taken on Date, the latest day when zero, or aggregated over the Days days ending on it:
+// daily amounts are summed, cumulative values give their increase and the other ones are averaged.
+// N limits the entries, zero keeping all of them, Ascending ranks from the lowest value and Region keeps only
+// the provinces of the given region
+type RankOptions struct {
+	Field          string
+	Per100k        bool
+	PopulationYear int
+	Date           time.Time
+	Days           int
+	N              int
+	Ascending      bool
+	Region         string
+}
+
+// Area in a ranking. Areas with the same value share the position and are sorted by name
+type RankEntry struct {
+	Posizione     int
+	Denominazione string
+	Sigla         string
+	Valore        float64
+}
+
+// Ranks the regions
+func RankRegions(data *[]RegionData, options RankOptions) (*[]RankEntry, error) {
+	kind, err := GetFieldKind(options.Field)
+	if err != nil {
+		return nil, err
+	}
+	if len(*data) < 21 {
+		return nil, fmt.Errorf("not enough regional data")
+	}
+
+	entries := make([]RankEntry, 0)
+	for i := 0; i < 21; i++ {
+		regionName := (*data)[i].Denominazione_regione
+		dates, values, _, err := regionToTimeseries(data, options.Field, i, i)
+		if err != nil {
+			return nil, err
+		}
+		value, ok, err := rankValue(dates, values, kind, options)
+		if err != nil {
+			return nil, fmt.Errorf("region %v: %v", regionName, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if options.Per100k {
+			population, _, err := GetRegionPopulation(regionName, options.PopulationYear)
+			if err != nil {
+				return nil, fmt.Errorf("region %v: %v", regionName, err)
+			}
+			value = value * 100000 / float64(population)
+		}
+		entries = append(entries, RankEntry{Denominazione: regionName, Valore: value})
+	}
+
+	return rankEntries(entries, options), nil
+}
+
+// Ranks the provinces, leaving out the rows of cases not yet assigned to a province
+func RankProvinces(data *[]ProvinceData, options RankOptions) (*[]RankEntry, error) {
+	kind, err := GetFieldKind(options.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, 0)
+	provinceIndexes := make(map[int][]int)
+	for i, v := range *data {
+		if v.Sigla_provincia == "" {
+			continue
+		}
+		if options.Region != "" && !sameAreaName(v.Denominazione_regione, options.Region) {
+			continue
+		}
+		if _, ok := provinceIndexes[v.Codice_provincia]; !ok {
+			order = append(order, v.Codice_provincia)
+		}
+		provinceIndexes[v.Codice_provincia] = append(provinceIndexes[v.Codice_provincia], i)
+	}
+
+	entries := make([]RankEntry, 0)
+	for _, code := range order {
+		indexes := provinceIndexes[code]
+		province := (*data)[indexes[0]]
+		dates, values, _, err := provinceToTimeseries(data, options.Field, &indexes)
+		if err != nil {
+			return nil, err
+		}
+		value, ok, err := rankValue(dates, values, kind, options)
+		if err != nil {
+			return nil, fmt.Errorf("province %v: %v", province.Denominazione_provincia, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if options.Per100k {
+			population, _, err := GetProvincePopulation(province.Sigla_provincia, options.PopulationYear)
+			if err != nil {
+				return nil, fmt.Errorf("province %v: %v", province.Denominazione_provincia, err)
+			}
+			value = value * 100000 / float64(population)
+		}
+		entries = append(entries, RankEntry{
+			Denominazione: province.Denominazione_provincia,
+			Sigla:         province.Sigla_provincia,
+			Valore:        value,
+		})
+	}
+
+	return rankEntries(entries, options), nil
+}
+
+// Returns the value of a series on the ranking day or window, false when the series has no value for that day
+func rankValue(dates *[]time.Time, values *[]float64, kind FieldKind, options RankOptions) (float64, bool, error) {
+	if options.Days < 0 {
+		return 0, false, fmt.Errorf("wrong number of days passed")
+	}
+	if len(*dates) == 0 {
+		return 0, false, nil
+	}
+
+	last := len(*dates) - 1
+	if !options.Date.IsZero() {
+		day := options.Date.Format("2006-01-02")
+		last = -1
+		for i, v := range *dates {
+			if v.Format("2006-01-02") == day {
+				last = i
+				break
+			}
+		}
+		if last < 0 {
+			return 0, false, nil
+		}
+	}
+	if options.Days == 0 {
+		return (*values)[last], true, nil
+	}
+
+	// the window starts from the first available day when the series is shorter
+	first := last - options.Days + 1
+	if first < 0 {
+		first = 0
+	}
+	switch kind {
+	case FieldCumulative:
+		if first == 0 {
+			return (*values)[last], true, nil
+		}
+		return (*values)[last] - (*values)[first-1], true, nil
+	case FieldFlow:
+		sum := 0.0
+		for _, v := range (*values)[first : last+1] {
+			sum += v
+		}
+		return sum, true, nil
+	default:
+		sum := 0.0
+		for _, v := range (*values)[first : last+1] {
+			sum += v
+		}
+		return sum / float64(last-first+1), true, nil
+	}
+}
+
+// Sorts the entries, assigns their positions and keeps the first N
+func rankEntries(entries []RankEntry, options RankOptions) *[]RankEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Valore != entries[j].Valore {
+			if options.Ascending {
+				return entries[i].Valore < entries[j].Valore
+			}
+			return entries[i].Valore > entries[j].Valore
+		}
+		return strings.ToLower(entries[i].Denominazione) < strings.ToLower(entries[j].Denominazione)
+	})
+
+	for i := range entries {
+		if i > 0 && entries[i].Valore == entries[i-1].Valore {
+			entries[i].Posizione = entries[i-1].Posizione
+		} else {
+			entries[i].Posizione = i + 1
+		}
+	}
+
+	if options.N > 0 && len(entries) > options.N {
+		entries = entries[:options.N]
+	}
+
+	return &entries
+}
+
+// Returns the index of the first provincial row of the latest day
+func firstLatestProvinceIndex(data *[]ProvinceData) int {
+	latestDay := dayOf((*data)[len(*data)-1].Data)
+	i := len(*data) - 1
+	for i > 0 && dayOf((*data)[i-1].Data) == latestDay {
+		i--
+	}
+
+	return i
+}
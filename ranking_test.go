@@ -0,0 +1,234 @@
+package covidgraphs
+
+import (
+	"testing"
+	"time"
+)
+
+// Regions in the order of the upstream data
+var testRegions = []string{"Abruzzo", "Basilicata", "Calabria", "Campania", "Emilia-Romagna", "Friuli Venezia Giulia",
+	"Lazio", "Liguria", "Lombardia", "Marche", "Molise", "P.A. Bolzano", "P.A. Trento", "Piemonte", "Puglia",
+	"Sardegna", "Sicilia", "Toscana", "Umbria", "Valle d'Aosta", "Veneto"}
+
+// Returns three days of regional data, every region without cases unless given
+func rankingRegions(cases, icu map[string][]int) *[]RegionData {
+	data := make([]RegionData, 0)
+	for day := 0; day < 3; day++ {
+		for _, regionName := range testRegions {
+			row := RegionData{Data: upstreamDate(day), Denominazione_regione: regionName}
+			if values, ok := cases[regionName]; ok {
+				row.Totale_casi = values[day]
+			}
+			if values, ok := icu[regionName]; ok {
+				row.Terapia_intensiva = values[day]
+			}
+			data = append(data, row)
+		}
+	}
+	return &data
+}
+
+// Checks the names, positions and values of a ranking
+func checkRanking(t *testing.T, ranking *[]RankEntry, want []RankEntry) {
+	t.Helper()
+	if len(*ranking) != len(want) {
+		t.Fatalf("got %+v, want %+v", *ranking, want)
+	}
+	for i, v := range *ranking {
+		if v != want[i] {
+			t.Fatalf("got %+v, want %+v", *ranking, want)
+		}
+	}
+}
+
+func TestRankRegions(t *testing.T) {
+	data := rankingRegions(map[string][]int{
+		"Lombardia": {100, 150, 170},
+		"Veneto":    {50, 120, 170},
+		"Piemonte":  {10, 20, 160},
+		"Lazio":     {0, 0, 5},
+	}, map[string][]int{
+		"Lombardia": {30, 60, 90},
+		"Veneto":    {40, 40, 40},
+	})
+
+	tests := []struct {
+		name    string
+		options RankOptions
+		want    []RankEntry
+	}{
+		{"latest day with ties sorted by name", RankOptions{Field: "totale_casi", N: 3}, []RankEntry{
+			{Posizione: 1, Denominazione: "Lombardia", Valore: 170},
+			{Posizione: 1, Denominazione: "Veneto", Valore: 170},
+			{Posizione: 3, Denominazione: "Piemonte", Valore: 160},
+		}},
+		{"ascending", RankOptions{Field: "totale_casi", N: 2, Ascending: true}, []RankEntry{
+			{Posizione: 1, Denominazione: "Abruzzo", Valore: 0},
+			{Posizione: 1, Denominazione: "Basilicata", Valore: 0},
+		}},
+		{"all of them", RankOptions{Field: "totale_casi"}, nil},
+		{"chosen day", RankOptions{Field: "totale_casi", Date: testDay(1), N: 3}, []RankEntry{
+			{Posizione: 1, Denominazione: "Lombardia", Valore: 150},
+			{Posizione: 2, Denominazione: "Veneto", Valore: 120},
+			{Posizione: 3, Denominazione: "Piemonte", Valore: 20},
+		}},
+		{"increase of a cumulative field over the window", RankOptions{Field: "totale_casi", Days: 2, N: 4}, []RankEntry{
+			{Posizione: 1, Denominazione: "Piemonte", Valore: 150},
+			{Posizione: 2, Denominazione: "Veneto", Valore: 120},
+			{Posizione: 3, Denominazione: "Lombardia", Valore: 70},
+			{Posizione: 4, Denominazione: "Lazio", Valore: 5},
+		}},
+		{"window longer than the series", RankOptions{Field: "totale_casi", Days: 10, N: 1}, []RankEntry{
+			{Posizione: 1, Denominazione: "Lombardia", Valore: 170},
+		}},
+		{"average of a stock over the window", RankOptions{Field: "terapia_intensiva", Days: 2, N: 2}, []RankEntry{
+			{Posizione: 1, Denominazione: "Lombardia", Valore: 75},
+			{Posizione: 2, Denominazione: "Veneto", Valore: 40},
+		}},
+		{"day without data", RankOptions{Field: "totale_casi", Date: testDay(5)}, []RankEntry{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ranking, err := RankRegions(data, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want == nil {
+				if len(*ranking) != len(testRegions) {
+					t.Fatalf("%d regions ranked, want %d", len(*ranking), len(testRegions))
+				}
+				return
+			}
+			checkRanking(t, ranking, test.want)
+		})
+	}
+}
+
+func TestRankRegionsErrors(t *testing.T) {
+	data := rankingRegions(nil, nil)
+	tests := []struct {
+		name    string
+		data    *[]RegionData
+		options RankOptions
+	}{
+		{"wrong field", data, RankOptions{Field: "campo"}},
+		{"negative days", data, RankOptions{Field: "totale_casi", Days: -1}},
+		{"not a full day of regions", &[]RegionData{{Data: upstreamDate(0)}}, RankOptions{Field: "totale_casi"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := RankRegions(test.data, test.options); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestRankProvinces(t *testing.T) {
+	type province struct {
+		code              int
+		region, name, abb string
+		cases             []int
+	}
+	provinces := []province{
+		{37, "Emilia-Romagna", "Bologna", "BO", []int{100, 200, 300}},
+		{40, "Emilia-Romagna", "Forlì-Cesena", "FC", []int{50, 150, 300}},
+		{36, "Emilia-Romagna", "Modena", "MO", []int{80, 100, 120}},
+		{998, "Emilia-Romagna", "In fase di definizione/aggiornamento", "", []int{500, 500, 500}},
+		{15, "Lombardia", "Milano", "MI", []int{400, 600, 900}},
+		{16, "Lombardia", "Bergamo", "BG", []int{300, 310, 320}},
+	}
+	data := make([]ProvinceData, 0)
+	for day := 0; day < 3; day++ {
+		for _, p := range provinces {
+			data = append(data, ProvinceData{
+				Data:                    upstreamDate(day),
+				Denominazione_regione:   p.region,
+				Codice_provincia:        p.code,
+				Denominazione_provincia: p.name,
+				Sigla_provincia:         p.abb,
+				Totale_casi:             p.cases[day],
+			})
+		}
+	}
+
+	tests := []struct {
+		name    string
+		options RankOptions
+		want    []RankEntry
+	}{
+		{"latest day without unassigned cases", RankOptions{Field: "totale_casi", N: 4}, []RankEntry{
+			{Posizione: 1, Denominazione: "Milano", Sigla: "MI", Valore: 900},
+			{Posizione: 2, Denominazione: "Bergamo", Sigla: "BG", Valore: 320},
+			{Posizione: 3, Denominazione: "Bologna", Sigla: "BO", Valore: 300},
+			{Posizione: 3, Denominazione: "Forlì-Cesena", Sigla: "FC", Valore: 300},
+		}},
+		{"provinces of a region", RankOptions{Field: "totale_casi", Region: "Emilia Romagna"}, []RankEntry{
+			{Posizione: 1, Denominazione: "Bologna", Sigla: "BO", Valore: 300},
+			{Posizione: 1, Denominazione: "Forlì-Cesena", Sigla: "FC", Valore: 300},
+			{Posizione: 3, Denominazione: "Modena", Sigla: "MO", Valore: 120},
+		}},
+		{"lowest increase over the window", RankOptions{Field: "totale_casi", Days: 1, N: 2, Ascending: true}, []RankEntry{
+			{Posizione: 1, Denominazione: "Bergamo", Sigla: "BG", Valore: 10},
+			{Posizione: 2, Denominazione: "Modena", Sigla: "MO", Valore: 20},
+		}},
+		{"region without provinces", RankOptions{Field: "totale_casi", Region: "Veneto"}, []RankEntry{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ranking, err := RankProvinces(&data, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRanking(t, ranking, test.want)
+		})
+	}
+
+	// the top ten of the latest day is sorted in the same way
+	topTen := GetTopTenProvincesTotaleContagi(&data)
+	names := []string{"Milano", "Bergamo", "Bologna", "Forlì-Cesena", "Modena"}
+	if len(*topTen) != len(names) {
+		t.Fatalf("top ten of %d provinces, want %d", len(*topTen), len(names))
+	}
+	for i, v := range *topTen {
+		if v.Denominazione_provincia != names[i] || !sameDay(v.Data, testDay(2)) {
+			t.Fatalf("top ten %v on %v, want %v on %v", v.Denominazione_provincia, v.Data, names[i], testDay(2))
+		}
+	}
+}
+
+func TestGetTopTenRegionsTotaleContagi(t *testing.T) {
+	cases := make(map[string][]int)
+	for i, regionName := range testRegions {
+		// the regions from Lazio are ranked by name, with the same cases
+		value := i
+		if i > 5 {
+			value = 5
+		}
+		cases[regionName] = []int{0, 0, 100 - value}
+	}
+	topTen := GetTopTenRegionsTotaleContagi(rankingRegions(cases, nil))
+
+	names := append(append([]string(nil), testRegions[:6]...), "Lazio", "Liguria", "Lombardia", "Marche")
+	if len(*topTen) != 10 {
+		t.Fatalf("top ten of %d regions", len(*topTen))
+	}
+	for i, v := range *topTen {
+		if v.Denominazione_regione != names[i] || !sameDay(v.Data, testDay(2)) {
+			t.Fatalf("top ten %v on %v, want %v on %v", v.Denominazione_regione, v.Data, names[i], testDay(2))
+		}
+	}
+}
+
+// Returns the i-th test day as written in the upstream data
+func upstreamDate(i int) string {
+	return testDay(i).Add(17 * time.Hour).Format("2006-01-02T15:04:05")
+}
+
+// Checks whether an upstream date string is on the given day
+func sameDay(date string, day time.Time) bool {
+	return dayOf(date) == day.Format("2006-01-02")
+}
@@ -0,0 +1,122 @@
+package covidgraphs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Days between the cases and the deaths compared by the lagged case fatality ratio when none is given
+const DefaultFatalityLag = 14
+
+// Ratio fields accepted by the series builders, in percent:
+// letalita_apparente is the apparent case fatality ratio, the deaths over the total cases,
+// letalita_ritardata compares the deaths with the total cases of DefaultFatalityLag days before,
+// letalita_ritardata_<giorni> with the ones of the given number of days before,
+// quota_ospedalizzati is the share of the current positives in hospital
+// and quota_terapia_intensiva the share of the hospitalised in intensive care
+var ratioFields = []string{"letalita_apparente", "letalita_ritardata", "quota_ospedalizzati", "quota_terapia_intensiva"}
+
+// Returns the days of delay of a lagged case fatality ratio field, false for the other fields
+func fatalityLag(fieldName string) (int, bool) {
+	fieldName = strings.ToLower(fieldName)
+	if fieldName == "letalita_ritardata" {
+		return DefaultFatalityLag, true
+	}
+	if !strings.HasPrefix(fieldName, "letalita_ritardata_") {
+		return 0, false
+	}
+	lag, err := strconv.Atoi(strings.TrimPrefix(fieldName, "letalita_ritardata_"))
+	if err != nil || lag < 0 {
+		return 0, false
+	}
+
+	return lag, true
+}
+
+// Checks whether the field is one of the ratio fields
+func isRatioField(fieldName string) bool {
+	if _, ok := fatalityLag(fieldName); ok {
+		return true
+	}
+	for _, v := range ratioFields {
+		if strings.ToLower(fieldName) == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the description of a ratio field as shown on plots
+func ratioName(fieldName string) string {
+	if lag, ok := fatalityLag(fieldName); ok {
+		return "Letalità a " + strconv.Itoa(lag) + " giorni (%)"
+	}
+	switch strings.ToLower(fieldName) {
+	case "letalita_apparente":
+		return "Letalità apparente (%)"
+	case "quota_ospedalizzati":
+		return "Positivi ospedalizzati (%)"
+	case "quota_terapia_intensiva":
+		return "Ospedalizzati in terapia intensiva (%)"
+	default:
+		return fieldName
+	}
+}
+
+// Smallest number of people a ratio is calculated on, ratios of fewer people swing too much to be meaningful
+const minRatioDenominator = 100
+
+// Calculates a ratio in percent, not defined when the denominator is below minRatioDenominator
+func ratio(numerator, denominator int) (float64, bool) {
+	if denominator < minRatioDenominator {
+		return 0, false
+	}
+
+	return float64(numerator) * 100 / float64(denominator), true
+}
+
+// Calculates the ratio field on the given day of the nation data
+func nationRatio(data *[]NationData, i int, fieldName string) (float64, bool) {
+	current := (*data)[i]
+	if lag, ok := fatalityLag(fieldName); ok {
+		if i < lag {
+			return 0, false
+		}
+		return ratio(current.Deceduti, (*data)[i-lag].Totale_casi)
+	}
+
+	switch strings.ToLower(fieldName) {
+	case "letalita_apparente":
+		return ratio(current.Deceduti, current.Totale_casi)
+	case "quota_ospedalizzati":
+		return ratio(current.Totale_ospedalizzati, current.Totale_positivi)
+	case "quota_terapia_intensiva":
+		return ratio(current.Terapia_intensiva, current.Totale_ospedalizzati)
+	default:
+		return 0, false
+	}
+}
+
+// Calculates the ratio field on the given day of the regional data
+func regionRatio(data *[]RegionData, i int, fieldName string) (float64, bool) {
+	current := (*data)[i]
+	if lag, ok := fatalityLag(fieldName); ok {
+		// the data holds a row for each region every day
+		if i < lag*21 {
+			return 0, false
+		}
+		return ratio(current.Deceduti, (*data)[i-lag*21].Totale_casi)
+	}
+
+	switch strings.ToLower(fieldName) {
+	case "letalita_apparente":
+		return ratio(current.Deceduti, current.Totale_casi)
+	case "quota_ospedalizzati":
+		return ratio(current.Totale_ospedalizzati, current.Totale_positivi)
+	case "quota_terapia_intensiva":
+		return ratio(current.Terapia_intensiva, current.Totale_ospedalizzati)
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,190 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period values are aggregated by
+type Period int
+
+const (
+	PeriodDay Period = iota
+	// ISO week, starting on Monday
+	PeriodWeek
+	// Calendar month
+	PeriodMonth
+)
+
+// Returns the name of the period
+func (p Period) String() string {
+	switch p {
+	case PeriodDay:
+		return "giorno"
+	case PeriodWeek:
+		return "settimana"
+	case PeriodMonth:
+		return "mese"
+	default:
+		return "sconosciuto"
+	}
+}
+
+// Returns the first day of the period containing the date
+func periodStart(date time.Time, period Period) time.Time {
+	year, month, day := date.Date()
+	switch period {
+	case PeriodWeek:
+		// Monday is the first day of ISO weeks
+		offset := (int(date.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, date.Location())
+	case PeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, date.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, date.Location())
+	}
+}
+
+// Returns the label of the period starting on the given day, as shown on plot axes
+func periodLabel(start time.Time, period Period) string {
+	switch period {
+	case PeriodWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-S%02d", year, week)
+	case PeriodMonth:
+		months := []string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"}
+		return fmt.Sprintf("%v %d", months[start.Month()-1], start.Year())
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
+// Aggregates a daily series by period, each value being dated with the first day of its period.
+// Daily amounts are summed, stocks and cumulative values take the last value of the period and rates are averaged,
+// ratios known by their parts are aggregated with ResampleRatio instead.
+// The last period may be incomplete
+func Resample(dates *[]time.Time, values *[]float64, kind FieldKind, period Period) (*[]time.Time, *[]float64, error) {
+	if len(*dates) != len(*values) {
+		return nil, nil, fmt.Errorf("dates and values have different lengths")
+	}
+	if period < PeriodDay || period > PeriodMonth {
+		return nil, nil, fmt.Errorf("wrong period passed")
+	}
+
+	resampledDates := make([]time.Time, 0)
+	resampledValues := make([]float64, 0)
+	count := 0
+	for i, date := range *dates {
+		start := periodStart(date, period)
+		if len(resampledDates) == 0 || !start.Equal(resampledDates[len(resampledDates)-1]) {
+			resampledDates = append(resampledDates, start)
+			resampledValues = append(resampledValues, 0)
+			count = 0
+		}
+
+		last := len(resampledValues) - 1
+		count++
+		switch kind {
+		case FieldFlow:
+			resampledValues[last] += (*values)[i]
+		case FieldRate:
+			resampledValues[last] += ((*values)[i] - resampledValues[last]) / float64(count)
+		default:
+			resampledValues[last] = (*values)[i]
+		}
+	}
+
+	return &resampledDates, &resampledValues, nil
+}
+
+// Aggregates a daily ratio by period as the percent of the sum of its numerators over the sum of its denominators,
+// like the positives over the tests of the positivity rate, each value being dated with the first day of its period.
+// Periods whose denominators sum to zero are left out
+func ResampleRatio(dates *[]time.Time, numerators, denominators *[]float64, period Period) (*[]time.Time, *[]float64, error) {
+	if len(*dates) != len(*numerators) || len(*dates) != len(*denominators) {
+		return nil, nil, fmt.Errorf("dates, numerators and denominators have different lengths")
+	}
+	if period < PeriodDay || period > PeriodMonth {
+		return nil, nil, fmt.Errorf("wrong period passed")
+	}
+
+	starts := make([]time.Time, 0)
+	sums := make([][2]float64, 0)
+	for i, date := range *dates {
+		start := periodStart(date, period)
+		if len(starts) == 0 || !start.Equal(starts[len(starts)-1]) {
+			starts = append(starts, start)
+			sums = append(sums, [2]float64{})
+		}
+		sums[len(sums)-1][0] += (*numerators)[i]
+		sums[len(sums)-1][1] += (*denominators)[i]
+	}
+
+	resampledDates := make([]time.Time, 0)
+	resampledValues := make([]float64, 0)
+	for i, start := range starts {
+		if sums[i][1] == 0 {
+			continue
+		}
+		resampledDates = append(resampledDates, start)
+		resampledValues = append(resampledValues, sums[i][0]*100/sums[i][1])
+	}
+
+	return &resampledDates, &resampledValues, nil
+}
+
+// Returns dates and values of the specified national data field aggregated by period.
+// Positivity rates are the ones of the positives and the tests of each period
+func NationSeriesResampled(data *[]NationData, fieldName string, period Period) (*[]time.Time, *[]float64, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, nil, err
+	}
+	dates, values, err := NationSeries(data, fieldName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isPositivityField(fieldName) {
+		parts := nationPositivitySeries(data, fieldName, 0)
+		return ResampleRatio(dates, &parts.numerators, &parts.denominators, period)
+	}
+	return Resample(dates, values, kind, period)
+}
+
+// Returns dates and values of the specified regional data field for the given region aggregated by period.
+// Positivity rates are the ones of the positives and the tests of each period
+func RegionSeriesResampled(data *[]RegionData, fieldName string, regionName string, period Period) (*[]time.Time, *[]float64, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, nil, err
+	}
+	dates, values, err := RegionSeries(data, fieldName, regionName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isPositivityField(fieldName) {
+		regionIndex, err := FindFirstOccurrenceRegion(data, "denominazione_regione", regionName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("region %v: %v", regionName, err)
+		}
+		parts := regionPositivitySeries(data, fieldName, regionIndex, regionIndex%21)
+		return ResampleRatio(dates, &parts.numerators, &parts.denominators, period)
+	}
+	return Resample(dates, values, kind, period)
+}
+
+// Returns dates and values of the specified provincial data field for the given province aggregated by period
+func ProvinceSeriesResampled(data *[]ProvinceData, fieldName string, provinceName string, period Period) (*[]time.Time, *[]float64, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, nil, err
+	}
+	dates, values, err := ProvinceSeries(data, fieldName, provinceName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return Resample(dates, values, kind, period)
+}
@@ -0,0 +1,72 @@
+package covidgraphs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResample(t *testing.T) {
+	// ten days, from Monday to the Wednesday of the following week
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		name   string
+		kind   FieldKind
+		period Period
+		dates  []time.Time
+		values []float64
+	}{
+		{"daily amounts are summed", FieldFlow, PeriodWeek, []time.Time{testDay(0), testDay(7)}, []float64{28, 27}},
+		{"stocks take the last value", FieldStock, PeriodWeek, []time.Time{testDay(0), testDay(7)}, []float64{7, 10}},
+		{"cumulative values take the last value", FieldCumulative, PeriodWeek, []time.Time{testDay(0), testDay(7)}, []float64{7, 10}},
+		{"rates are averaged", FieldRate, PeriodWeek, []time.Time{testDay(0), testDay(7)}, []float64{4, 9}},
+		{"months", FieldFlow, PeriodMonth, []time.Time{time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}, []float64{55}},
+		{"days are left as they are", FieldFlow, PeriodDay, testDays(10), values},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days := testDays(len(values))
+			dates, resampled, err := Resample(&days, &values, test.kind, test.period)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*dates) != len(test.dates) || len(*resampled) != len(test.values) {
+				t.Fatalf("got %v %v, want %v %v", *dates, *resampled, test.dates, test.values)
+			}
+			for i := range test.dates {
+				if !(*dates)[i].Equal(test.dates[i]) || !sameValue((*resampled)[i], test.values[i]) {
+					t.Fatalf("got %v %v, want %v %v", *dates, *resampled, test.dates, test.values)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleErrors(t *testing.T) {
+	days := testDays(3)
+	if _, _, err := Resample(&days, &[]float64{1, 2}, FieldFlow, PeriodWeek); err == nil {
+		t.Error("expected an error for different lengths")
+	}
+	if _, _, err := Resample(&days, &[]float64{1, 2, 3}, FieldFlow, Period(7)); err == nil {
+		t.Error("expected an error for a wrong period")
+	}
+}
+
+func TestResampleRatio(t *testing.T) {
+	// the rate of a week is the one of its sums, not the average of the daily rates
+	days := testDays(9)
+	numerators := []float64{1, 1, 1, 1, 1, 1, 94, 0, 0}
+	denominators := []float64{10, 10, 10, 10, 10, 10, 940, 0, 0}
+	dates, values, err := ResampleRatio(&days, &numerators, &denominators, PeriodWeek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second week has no tests and is left out
+	if len(*dates) != 1 || !(*dates)[0].Equal(testDay(0)) {
+		t.Fatalf("got dates %v, want only %v", *dates, testDay(0))
+	}
+	if !sameValue((*values)[0], 10) {
+		t.Fatalf("got rate %v, want 10", (*values)[0])
+	}
+}
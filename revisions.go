@@ -0,0 +1,450 @@
+package covidgraphs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the datasets whose versions are stored
+const (
+	DatasetNazione  = "nazione"
+	DatasetRegioni  = "regioni"
+	DatasetProvince = "province"
+)
+
+// Layout of the fetch times in the names of the stored versions
+const revisionTimeLayout = "20060102T150405.000000000Z"
+
+// Directory keeping every version of the upstream datasets, one compressed JSON file each named after its fetch time
+type RevisionStore struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// Returns a store keeping the versions in the given directory, creating it when missing
+func NewRevisionStore(dir string) (*RevisionStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating revisions directory: %v", err)
+	}
+
+	return &RevisionStore{dir: dir}, nil
+}
+
+// Returns the fetch times of the stored versions of the dataset, from the oldest
+func (s *RevisionStore) Revisions(dataset string) ([]time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.revisions(dataset)
+}
+
+// Lists the versions of the dataset, the store must be locked
+func (s *RevisionStore) revisions(dataset string) ([]time.Time, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading revisions directory: %v", err)
+	}
+
+	fetchTimes := make([]time.Time, 0)
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, dataset+"-") || !strings.HasSuffix(name, ".json.gz") {
+			continue
+		}
+		fetched, err := time.Parse(revisionTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, dataset+"-"), ".json.gz"))
+		if err != nil {
+			continue
+		}
+		fetchTimes = append(fetchTimes, fetched)
+	}
+	sort.Slice(fetchTimes, func(i, j int) bool {
+		return fetchTimes[i].Before(fetchTimes[j])
+	})
+
+	return fetchTimes, nil
+}
+
+// Returns the path of the version of the dataset fetched at the given time
+func (s *RevisionStore) path(dataset string, fetched time.Time) string {
+	return filepath.Join(s.dir, dataset+"-"+fetched.UTC().Format(revisionTimeLayout)+".json.gz")
+}
+
+// Reads the uncompressed content of a version, the store must be locked
+func (s *RevisionStore) read(dataset string, fetched time.Time) ([]byte, error) {
+	f, err := os.Open(s.path(dataset, fetched))
+	if err != nil {
+		return nil, fmt.Errorf("error while opening revision: %v", err)
+	}
+	defer f.Close()
+
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading revision: %v", err)
+	}
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading revision: %v", err)
+	}
+	return content, nil
+}
+
+// Stores the data as a new version of the dataset unless it equals the latest one, returning whether it was stored
+func (s *RevisionStore) save(dataset string, data interface{}, fetched time.Time) (bool, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return false, fmt.Errorf("error while encoding revision: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fetchTimes, err := s.revisions(dataset)
+	if err != nil {
+		return false, err
+	}
+	if len(fetchTimes) > 0 {
+		latest := fetchTimes[len(fetchTimes)-1]
+		if !fetched.After(latest) {
+			return false, fmt.Errorf("revision fetched on %v is not newer than the latest one", fetched)
+		}
+		previous, err := s.read(dataset, latest)
+		if err != nil {
+			return false, err
+		}
+		if bytes.Equal(previous, content) {
+			return false, nil
+		}
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err = writer.Write(content)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return false, fmt.Errorf("error while compressing revision: %v", err)
+	}
+
+	err = ioutil.WriteFile(s.path(dataset, fetched), compressed.Bytes(), 0644)
+	if err != nil {
+		return false, fmt.Errorf("error while writing revision: %v", err)
+	}
+	return true, nil
+}
+
+// Loads into data the latest version of the dataset fetched not after the given time, returning its fetch time
+func (s *RevisionStore) loadAsOf(dataset string, date time.Time, data interface{}) (time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fetchTimes, err := s.revisions(dataset)
+	if err != nil {
+		return time.Time{}, err
+	}
+	index := sort.Search(len(fetchTimes), func(i int) bool {
+		return fetchTimes[i].After(date)
+	}) - 1
+	if index < 0 {
+		return time.Time{}, fmt.Errorf("no revision of %v fetched before %v", dataset, date)
+	}
+
+	content, err := s.read(dataset, fetchTimes[index])
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = json.Unmarshal(content, data)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error while decoding revision: %v", err)
+	}
+	return fetchTimes[index], nil
+}
+
+// Stores the national data fetched at the given time, returning false when it equals the latest version
+func (s *RevisionStore) SaveNation(data *[]NationData, fetched time.Time) (bool, error) {
+	return s.save(DatasetNazione, data, fetched)
+}
+
+// Stores the regional data fetched at the given time, returning false when it equals the latest version
+func (s *RevisionStore) SaveRegions(data *[]RegionData, fetched time.Time) (bool, error) {
+	return s.save(DatasetRegioni, data, fetched)
+}
+
+// Stores the provincial data fetched at the given time, returning false when it equals the latest version
+func (s *RevisionStore) SaveProvinces(data *[]ProvinceData, fetched time.Time) (bool, error) {
+	return s.save(DatasetProvince, data, fetched)
+}
+
+// Retrieves the national data and stores it when it differs from the latest version
+func (s *RevisionStore) FetchNation() (*[]NationData, error) {
+	data, err := GetNation()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.SaveNation(data, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Retrieves the regional data and stores it when it differs from the latest version
+func (s *RevisionStore) FetchRegions() (*[]RegionData, error) {
+	data, err := GetRegions()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.SaveRegions(data, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Retrieves the provincial data and stores it when it differs from the latest version
+func (s *RevisionStore) FetchProvinces() (*[]ProvinceData, error) {
+	data, err := GetProvinces()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.SaveProvinces(data, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Returns the national data as it was fetched on the given time, along with the fetch time of that version
+func (s *RevisionStore) NationAsOf(date time.Time) (*[]NationData, time.Time, error) {
+	var data []NationData
+	fetched, err := s.loadAsOf(DatasetNazione, date, &data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return &data, fetched, nil
+}
+
+// Returns the regional data as it was fetched on the given time, along with the fetch time of that version
+func (s *RevisionStore) RegionsAsOf(date time.Time) (*[]RegionData, time.Time, error) {
+	var data []RegionData
+	fetched, err := s.loadAsOf(DatasetRegioni, date, &data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return &data, fetched, nil
+}
+
+// Returns the provincial data as it was fetched on the given time, along with the fetch time of that version
+func (s *RevisionStore) ProvincesAsOf(date time.Time) (*[]ProvinceData, time.Time, error) {
+	var data []ProvinceData
+	fetched, err := s.loadAsOf(DatasetProvince, date, &data)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return &data, fetched, nil
+}
+
+// How a row differs between two versions
+type ChangeType int
+
+const (
+	RowAdded ChangeType = iota
+	RowRemoved
+	RowChanged
+)
+
+// Returns the description of the change
+func (t ChangeType) String() string {
+	switch t {
+	case RowAdded:
+		return "aggiunta"
+	case RowRemoved:
+		return "rimossa"
+	case RowChanged:
+		return "modificata"
+	default:
+		return "sconosciuta"
+	}
+}
+
+// Field changed between two versions of a row, Differenza is NaN for text fields
+type FieldChange struct {
+	Campo      string
+	Prima      string
+	Dopo       string
+	Differenza float64
+}
+
+// Row added, removed or changed between two versions, Campi lists the changed fields of changed rows
+type RowDiff struct {
+	Data                    string
+	Denominazione_regione   string
+	Denominazione_provincia string
+	Tipo                    ChangeType
+	Campi                   []FieldChange
+}
+
+// Row of a dataset identified by its day and area
+type revisionRow struct {
+	key      string
+	data     string
+	region   string
+	province string
+	value    reflect.Value
+}
+
+// Returns the rows of changed, added and removed days and areas, sorted by date and area
+func diffRows(before, after []revisionRow) *[]RowDiff {
+	previous := make(map[string]revisionRow)
+	for _, v := range before {
+		previous[v.key] = v
+	}
+
+	diffs := make([]RowDiff, 0)
+	seen := make(map[string]bool)
+	for _, v := range after {
+		seen[v.key] = true
+		diff := RowDiff{Data: v.data, Denominazione_regione: v.region, Denominazione_provincia: v.province}
+		old, ok := previous[v.key]
+		if !ok {
+			diff.Tipo = RowAdded
+			diffs = append(diffs, diff)
+			continue
+		}
+
+		diff.Tipo = RowChanged
+		diff.Campi = fieldChanges(old.value, v.value)
+		if len(diff.Campi) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, v := range before {
+		if !seen[v.key] {
+			diffs = append(diffs, RowDiff{Data: v.data, Denominazione_regione: v.region, Denominazione_provincia: v.province, Tipo: RowRemoved})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if dayOf(diffs[i].Data) != dayOf(diffs[j].Data) {
+			return dayOf(diffs[i].Data) < dayOf(diffs[j].Data)
+		}
+		if diffs[i].Denominazione_regione != diffs[j].Denominazione_regione {
+			return diffs[i].Denominazione_regione < diffs[j].Denominazione_regione
+		}
+		return diffs[i].Denominazione_provincia < diffs[j].Denominazione_provincia
+	})
+
+	return &diffs
+}
+
+// Returns the fields with different values in two rows of the same type, named as in the upstream data
+func fieldChanges(before, after reflect.Value) []FieldChange {
+	changes := make([]FieldChange, 0)
+	for i := 0; i < after.NumField(); i++ {
+		field := after.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		old := before.Field(i)
+		current := after.Field(i)
+		switch current.Kind() {
+		case reflect.Int, reflect.Int64:
+			if old.Int() != current.Int() {
+				changes = append(changes, FieldChange{
+					Campo:      name,
+					Prima:      strconv.FormatInt(old.Int(), 10),
+					Dopo:       strconv.FormatInt(current.Int(), 10),
+					Differenza: float64(current.Int() - old.Int()),
+				})
+			}
+		case reflect.Float64:
+			if old.Float() != current.Float() {
+				changes = append(changes, FieldChange{
+					Campo:      name,
+					Prima:      strconv.FormatFloat(old.Float(), 'f', -1, 64),
+					Dopo:       strconv.FormatFloat(current.Float(), 'f', -1, 64),
+					Differenza: current.Float() - old.Float(),
+				})
+			}
+		case reflect.String:
+			if old.String() != current.String() {
+				changes = append(changes, FieldChange{Campo: name, Prima: old.String(), Dopo: current.String(), Differenza: math.NaN()})
+			}
+		}
+	}
+
+	return changes
+}
+
+// Compares two versions of the national data
+func DiffNation(before, after *[]NationData) *[]RowDiff {
+	rows := func(data *[]NationData) []revisionRow {
+		result := make([]revisionRow, len(*data))
+		for i, v := range *data {
+			result[i] = revisionRow{key: dayOf(v.Data), data: v.Data, value: reflect.ValueOf(v)}
+		}
+		return result
+	}
+
+	return diffRows(rows(before), rows(after))
+}
+
+// Compares two versions of the regional data
+func DiffRegions(before, after *[]RegionData) *[]RowDiff {
+	rows := func(data *[]RegionData) []revisionRow {
+		result := make([]revisionRow, len(*data))
+		for i, v := range *data {
+			result[i] = revisionRow{
+				key:    fmt.Sprintf("%v|%d|%v", dayOf(v.Data), v.Codice_regione, v.Denominazione_regione),
+				data:   v.Data,
+				region: v.Denominazione_regione,
+				value:  reflect.ValueOf(v),
+			}
+		}
+		return result
+	}
+
+	return diffRows(rows(before), rows(after))
+}
+
+// Compares two versions of the provincial data
+func DiffProvinces(before, after *[]ProvinceData) *[]RowDiff {
+	rows := func(data *[]ProvinceData) []revisionRow {
+		result := make([]revisionRow, len(*data))
+		for i, v := range *data {
+			result[i] = revisionRow{
+				key:      fmt.Sprintf("%v|%d|%d|%v", dayOf(v.Data), v.Codice_regione, v.Codice_provincia, v.Denominazione_provincia),
+				data:     v.Data,
+				region:   v.Denominazione_regione,
+				province: v.Denominazione_provincia,
+				value:    reflect.ValueOf(v),
+			}
+		}
+		return result
+	}
+
+	return diffRows(rows(before), rows(after))
+}
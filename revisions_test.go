@@ -0,0 +1,159 @@
+package covidgraphs
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRevisionStore(t *testing.T) {
+	store, err := NewRevisionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2021, 1, 10, 18, 0, 0, 0, time.UTC)
+	second := first.Add(2 * time.Hour)
+	third := first.Add(24 * time.Hour)
+
+	original := []NationData{{Data: upstreamDate(0), Totale_casi: 100}, {Data: upstreamDate(1), Totale_casi: 150}}
+	revised := []NationData{{Data: upstreamDate(0), Totale_casi: 110}, {Data: upstreamDate(1), Totale_casi: 150}}
+
+	if saved, err := store.SaveNation(&original, first); err != nil || !saved {
+		t.Fatalf("first version saved %v: %v", saved, err)
+	}
+	// the same data fetched again is not a new version
+	if saved, err := store.SaveNation(&original, second); err != nil || saved {
+		t.Fatalf("unchanged version saved %v: %v", saved, err)
+	}
+	// versions can only be added after the latest one
+	if _, err := store.SaveNation(&revised, first); err == nil {
+		t.Fatal("expected an error for a version fetched with the latest one")
+	}
+	if _, err := store.SaveNation(&revised, first.Add(-time.Hour)); err == nil {
+		t.Fatal("expected an error for a version fetched before the latest one")
+	}
+	if saved, err := store.SaveNation(&revised, third); err != nil || !saved {
+		t.Fatalf("revised version saved %v: %v", saved, err)
+	}
+
+	revisions, err := store.Revisions(DatasetNazione)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || !revisions[0].Equal(first) || !revisions[1].Equal(third) {
+		t.Fatalf("revisions %v, want %v and %v", revisions, first, third)
+	}
+
+	tests := []struct {
+		name    string
+		asOf    time.Time
+		fetched time.Time
+		cases   int
+	}{
+		{"on the first fetch", first, first, 100},
+		{"between the two versions", second, first, 100},
+		{"after the latest version", third.Add(time.Hour), third, 110},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, fetched, err := store.NationAsOf(test.asOf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fetched.Equal(test.fetched) || len(*data) != 2 || (*data)[0].Totale_casi != test.cases {
+				t.Errorf("version of %v with %v, want the one of %v with %d cases", fetched, *data, test.fetched, test.cases)
+			}
+		})
+	}
+
+	if _, _, err := store.NationAsOf(first.Add(-time.Minute)); err == nil {
+		t.Error("expected an error before the first version")
+	}
+	// each dataset has its own versions
+	if _, _, err := store.RegionsAsOf(third); err == nil {
+		t.Error("expected an error for a dataset without versions")
+	}
+}
+
+// Checks the rows of a diff, only the fields of changed rows are compared
+func checkDiff(t *testing.T, diffs *[]RowDiff, want []RowDiff) {
+	t.Helper()
+	if len(*diffs) != len(want) {
+		t.Fatalf("got %+v, want %+v", *diffs, want)
+	}
+	for i, d := range *diffs {
+		w := want[i]
+		if d.Data != w.Data || d.Denominazione_regione != w.Denominazione_regione ||
+			d.Denominazione_provincia != w.Denominazione_provincia || d.Tipo != w.Tipo || len(d.Campi) != len(w.Campi) {
+			t.Fatalf("row %d is %+v, want %+v", i, d, w)
+		}
+		for j, c := range d.Campi {
+			if c.Campo != w.Campi[j].Campo || c.Prima != w.Campi[j].Prima || c.Dopo != w.Campi[j].Dopo || !sameValue(c.Differenza, w.Campi[j].Differenza) {
+				t.Errorf("field change %+v of row %d, want %+v", c, i, w.Campi[j])
+			}
+		}
+	}
+}
+
+func TestDiffNation(t *testing.T) {
+	before := []NationData{
+		{Data: upstreamDate(0), Totale_casi: 100, Deceduti: 5},
+		{Data: upstreamDate(1), Totale_casi: 150, Deceduti: 6},
+		{Data: upstreamDate(2), Totale_casi: 180, Deceduti: 8},
+	}
+	after := []NationData{
+		{Data: upstreamDate(0), Totale_casi: 100, Deceduti: 5},
+		{Data: upstreamDate(1), Totale_casi: 145, Deceduti: 7, Note_it: "ricalcolo"},
+		{Data: upstreamDate(3), Totale_casi: 200, Deceduti: 9},
+	}
+
+	checkDiff(t, DiffNation(&before, &after), []RowDiff{
+		{Data: upstreamDate(1), Tipo: RowChanged, Campi: []FieldChange{
+			{Campo: "deceduti", Prima: "6", Dopo: "7", Differenza: 1},
+			{Campo: "totale_casi", Prima: "150", Dopo: "145", Differenza: -5},
+			{Campo: "note_it", Prima: "", Dopo: "ricalcolo", Differenza: math.NaN()},
+		}},
+		{Data: upstreamDate(2), Tipo: RowRemoved},
+		{Data: upstreamDate(3), Tipo: RowAdded},
+	})
+	checkDiff(t, DiffNation(&before, &before), []RowDiff{})
+}
+
+func TestDiffRegions(t *testing.T) {
+	before := []RegionData{
+		{Data: upstreamDate(0), Codice_regione: 5, Denominazione_regione: "Veneto", Terapia_intensiva: 10, Lat: 45.4},
+		{Data: upstreamDate(0), Codice_regione: 3, Denominazione_regione: "Lombardia", Terapia_intensiva: 20},
+	}
+	after := []RegionData{
+		{Data: upstreamDate(0), Codice_regione: 5, Denominazione_regione: "Veneto", Terapia_intensiva: 8, Lat: 45.5},
+		{Data: upstreamDate(0), Codice_regione: 3, Denominazione_regione: "Lombardia", Terapia_intensiva: 20},
+		{Data: upstreamDate(0), Codice_regione: 1, Denominazione_regione: "Piemonte", Terapia_intensiva: 4},
+	}
+
+	checkDiff(t, DiffRegions(&before, &after), []RowDiff{
+		{Data: upstreamDate(0), Denominazione_regione: "Piemonte", Tipo: RowAdded},
+		{Data: upstreamDate(0), Denominazione_regione: "Veneto", Tipo: RowChanged, Campi: []FieldChange{
+			{Campo: "lat", Prima: "45.4", Dopo: "45.5", Differenza: 45.5 - 45.4},
+			{Campo: "terapia_intensiva", Prima: "10", Dopo: "8", Differenza: -2},
+		}},
+	})
+}
+
+func TestDiffProvinces(t *testing.T) {
+	before := []ProvinceData{
+		{Data: upstreamDate(0), Codice_regione: 8, Denominazione_regione: "Emilia-Romagna", Codice_provincia: 37, Denominazione_provincia: "Bologna", Totale_casi: 50},
+		{Data: upstreamDate(0), Codice_regione: 8, Denominazione_regione: "Emilia-Romagna", Codice_provincia: 999, Denominazione_provincia: "In fase di definizione/aggiornamento", Totale_casi: 7},
+	}
+	after := []ProvinceData{
+		{Data: upstreamDate(0), Codice_regione: 8, Denominazione_regione: "Emilia-Romagna", Codice_provincia: 37, Denominazione_provincia: "Bologna", Totale_casi: 57},
+		{Data: upstreamDate(0), Codice_regione: 8, Denominazione_regione: "Emilia-Romagna", Codice_provincia: 40, Denominazione_provincia: "Forlì-Cesena", Totale_casi: 3},
+	}
+
+	checkDiff(t, DiffProvinces(&before, &after), []RowDiff{
+		{Data: upstreamDate(0), Denominazione_regione: "Emilia-Romagna", Denominazione_provincia: "Bologna", Tipo: RowChanged, Campi: []FieldChange{
+			{Campo: "totale_casi", Prima: "50", Dopo: "57", Differenza: 7},
+		}},
+		{Data: upstreamDate(0), Denominazione_regione: "Emilia-Romagna", Denominazione_provincia: "Forlì-Cesena", Tipo: RowAdded},
+		{Data: upstreamDate(0), Denominazione_regione: "Emilia-Romagna", Denominazione_provincia: "In fase di definizione/aggiornamento", Tipo: RowRemoved},
+	})
+}
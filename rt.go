@@ -0,0 +1,260 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Parameters of the Rt estimation with the renewal method of Cori et al. (2013).
+// The serial interval is a gamma distribution with the given mean and standard deviation in days, discretised per day,
+// unless SerialInterval is set: then its element i is the probability of a serial interval of i+1 days
+type RtConfig struct {
+	SerialIntervalMean float64
+	SerialIntervalSd   float64
+	SerialInterval     []float64
+	Window             int
+	PriorMean          float64
+	PriorSd            float64
+	CredibleInterval   float64
+}
+
+// Rt estimate for the window ending on the given day
+type RtEstimate struct {
+	Data      time.Time
+	Rt        float64
+	Inferiore float64
+	Superiore float64
+}
+
+// Returns the configuration used by default: the serial interval estimated in Lombardy by Cereda et al. (2020),
+// a weekly window and the EpiEstim prior with mean and standard deviation 5
+func DefaultRtConfig() RtConfig {
+	return RtConfig{
+		SerialIntervalMean: 6.6,
+		SerialIntervalSd:   4.88,
+		Window:             7,
+		PriorMean:          5,
+		PriorSd:            5,
+		CredibleInterval:   0.95,
+	}
+}
+
+// Returns the serial interval distribution starting from 1 day
+func (config RtConfig) serialInterval() ([]float64, error) {
+	if len(config.SerialInterval) > 0 {
+		sum := 0.0
+		for _, v := range config.SerialInterval {
+			if v < 0 {
+				return nil, fmt.Errorf("negative serial interval probability")
+			}
+			sum += v
+		}
+		if sum == 0 {
+			return nil, fmt.Errorf("empty serial interval distribution")
+		}
+		weights := make([]float64, len(config.SerialInterval))
+		for i, v := range config.SerialInterval {
+			weights[i] = v / sum
+		}
+		return weights, nil
+	}
+
+	if config.SerialIntervalMean <= 0 || config.SerialIntervalSd <= 0 {
+		return nil, fmt.Errorf("wrong serial interval parameters")
+	}
+	shape := math.Pow(config.SerialIntervalMean/config.SerialIntervalSd, 2)
+	scale := config.SerialIntervalSd * config.SerialIntervalSd / config.SerialIntervalMean
+
+	// each day takes the probability of the interval around it, day 0 is left out and the rest renormalised
+	weights := make([]float64, 0)
+	sum := 0.0
+	for k := 1; gammaCDF(float64(k)-0.5, shape, scale) < 0.999; k++ {
+		w := gammaCDF(float64(k)+0.5, shape, scale) - gammaCDF(float64(k)-0.5, shape, scale)
+		weights = append(weights, w)
+		sum += w
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+
+	return weights, nil
+}
+
+// Estimates Rt for each day from the daily new cases, negative values are treated as zero.
+// Days without enough previous cases to estimate the infection pressure are skipped,
+// when there are none left an error is returned
+func EstimateRt(dates *[]time.Time, cases *[]float64, config RtConfig) (*[]RtEstimate, error) {
+	if len(*dates) != len(*cases) {
+		return nil, fmt.Errorf("dates and cases have different lengths")
+	}
+	if config.Window < 1 {
+		return nil, fmt.Errorf("wrong window passed")
+	}
+	if config.PriorMean <= 0 || config.PriorSd <= 0 {
+		return nil, fmt.Errorf("wrong prior passed")
+	}
+	if config.CredibleInterval <= 0 || config.CredibleInterval >= 1 {
+		return nil, fmt.Errorf("wrong credible interval passed")
+	}
+	weights, err := config.serialInterval()
+	if err != nil {
+		return nil, err
+	}
+	if len(*cases) <= config.Window {
+		return nil, fmt.Errorf("not enough days to estimate Rt")
+	}
+
+	incidence := make([]float64, len(*cases))
+	for i, v := range *cases {
+		incidence[i] = math.Max(v, 0)
+	}
+
+	// infection pressure of the previous cases on each day
+	pressure := make([]float64, len(incidence))
+	for t := range incidence {
+		for s := 1; s <= len(weights) && s <= t; s++ {
+			pressure[t] += incidence[t-s] * weights[s-1]
+		}
+	}
+
+	priorShape := math.Pow(config.PriorMean/config.PriorSd, 2)
+	priorScale := config.PriorSd * config.PriorSd / config.PriorMean
+	lowerProbability := (1 - config.CredibleInterval) / 2
+
+	estimates := make([]RtEstimate, 0)
+	for t := config.Window; t < len(incidence); t++ {
+		casesSum := 0.0
+		pressureSum := 0.0
+		for s := t - config.Window + 1; s <= t; s++ {
+			casesSum += incidence[s]
+			pressureSum += pressure[s]
+		}
+		if pressureSum == 0 {
+			continue
+		}
+
+		shape := priorShape + casesSum
+		scale := 1 / (1/priorScale + pressureSum)
+		estimates = append(estimates, RtEstimate{
+			Data:      (*dates)[t],
+			Rt:        shape * scale,
+			Inferiore: gammaQuantile(lowerProbability, shape, scale),
+			Superiore: gammaQuantile(1-lowerProbability, shape, scale),
+		})
+	}
+	if len(estimates) == 0 {
+		return nil, fmt.Errorf("not enough cases to estimate Rt")
+	}
+
+	return &estimates, nil
+}
+
+// Estimates the national Rt from the daily new cases
+func NationRt(data *[]NationData, config RtConfig) (*[]RtEstimate, error) {
+	dates, cases, err := NationSeries(data, "nuovi_positivi")
+	if err != nil {
+		return nil, err
+	}
+
+	return EstimateRt(dates, cases, config)
+}
+
+// Estimates Rt of the given region from the daily new cases
+func RegionRt(data *[]RegionData, regionName string, config RtConfig) (*[]RtEstimate, error) {
+	dates, cases, err := RegionSeries(data, "nuovi_positivi", regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return EstimateRt(dates, cases, config)
+}
+
+// Estimates Rt of the given province from the daily new cases
+func ProvinceRt(data *[]ProvinceData, provinceName string, config RtConfig) (*[]RtEstimate, error) {
+	dates, cases, err := ProvinceSeries(data, "nuovi_positivi", provinceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return EstimateRt(dates, cases, config)
+}
+
+// Calculates the cumulative distribution function of a gamma distribution
+func gammaCDF(x, shape, scale float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+
+	return regularizedGammaP(shape, x/scale)
+}
+
+// Calculates the quantile of a gamma distribution by bisection
+func gammaQuantile(p, shape, scale float64) float64 {
+	mean := shape * scale
+	sd := math.Sqrt(shape) * scale
+	low, high := 0.0, mean+10*sd
+	for gammaCDF(high, shape, scale) < p {
+		high *= 2
+	}
+
+	for i := 0; i < 100 && high-low > 1e-10*high; i++ {
+		middle := (low + high) / 2
+		if gammaCDF(middle, shape, scale) < p {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+
+	return (low + high) / 2
+}
+
+// Calculates the regularized lower incomplete gamma function P(a, x),
+// with the series expansion below a+1 and the continued fraction above
+func regularizedGammaP(a, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	lgammaA, _ := math.Lgamma(a)
+	logPrefix := a*math.Log(x) - x - lgammaA
+
+	if x < a+1 {
+		term := 1 / a
+		sum := term
+		for n := 1; n < 1000000; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return math.Min(1, sum*math.Exp(logPrefix))
+	}
+
+	// modified Lentz's method
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < 1000000; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Max(0, 1-math.Exp(logPrefix)*h)
+}
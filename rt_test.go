@@ -0,0 +1,136 @@
+package covidgraphs
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegularizedGammaP(t *testing.T) {
+	tests := []struct {
+		a, x, want float64
+	}{
+		// P(1, x) is the exponential distribution function
+		{1, 0.5, 1 - math.Exp(-0.5)},
+		{1, 5, 1 - math.Exp(-5)},
+		// P(1/2, x) is erf(√x)
+		{0.5, 0.2, math.Erf(math.Sqrt(0.2))},
+		{0.5, 3, math.Erf(math.Sqrt(3))},
+		// P(n, x) for integers is 1 - e^-x Σ x^k/k!
+		{3, 2, 1 - math.Exp(-2)*(1+2+2)},
+		{3, 8, 1 - math.Exp(-8)*(1+8+32)},
+		{5, 0, 0},
+	}
+
+	for _, test := range tests {
+		if got := regularizedGammaP(test.a, test.x); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("P(%v, %v) = %v, want %v", test.a, test.x, got, test.want)
+		}
+	}
+}
+
+func TestGammaQuantile(t *testing.T) {
+	tests := []struct {
+		p, shape, scale, want float64
+	}{
+		// exponential distribution
+		{0.5, 1, 2, 2 * math.Ln2},
+		{0.975, 1, 0.5, -0.5 * math.Log(0.025)},
+		// χ² with 4 degrees of freedom
+		{0.025, 2, 2, 0.484419},
+		{0.975, 2, 2, 11.143287},
+	}
+
+	for _, test := range tests {
+		if got := gammaQuantile(test.p, test.shape, test.scale); math.Abs(got-test.want) > 1e-6 {
+			t.Errorf("quantile %v of gamma(%v, %v) = %v, want %v", test.p, test.shape, test.scale, got, test.want)
+		}
+	}
+}
+
+func TestEstimateRtConstantIncidence(t *testing.T) {
+	cases := make([]float64, 60)
+	for i := range cases {
+		cases[i] = 1000
+	}
+	days := testDays(len(cases))
+	estimates, err := EstimateRt(&days, &cases, DefaultRtConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := (*estimates)[len(*estimates)-1]
+	if !last.Data.Equal(testDay(59)) {
+		t.Errorf("last estimate on %v, want %v", last.Data, testDay(59))
+	}
+	if math.Abs(last.Rt-1) > 0.01 {
+		t.Errorf("Rt %v, want about 1", last.Rt)
+	}
+	if !(last.Inferiore < last.Rt && last.Rt < last.Superiore) || last.Superiore-last.Inferiore > 0.1 {
+		t.Errorf("credible interval [%v, %v] around %v", last.Inferiore, last.Superiore, last.Rt)
+	}
+}
+
+func TestEstimateRtPosterior(t *testing.T) {
+	// with a serial interval of one day and a window of one day, the cases of the previous day are the whole
+	// infection pressure: the posterior is a gamma with the shape of the prior plus the cases and the rate
+	// of the prior plus the pressure
+	config := RtConfig{SerialInterval: []float64{1}, Window: 1, PriorMean: 1, PriorSd: 1, CredibleInterval: 0.95}
+	tests := []struct {
+		name             string
+		cases            []float64
+		rt, lower, upper float64
+	}{
+		// gamma(1, 1/2), an exponential
+		{"no new cases", []float64{1, 0}, 0.5, -0.5 * math.Log(0.975), -0.5 * math.Log(0.025)},
+		// gamma(2, 1/2), a χ² with 4 degrees of freedom divided by 4
+		{"as many new cases", []float64{1, 1}, 1, 0.484419 / 4, 11.143287 / 4},
+		// negative recounts are treated as zero
+		{"negative cases", []float64{1, -5}, 0.5, -0.5 * math.Log(0.975), -0.5 * math.Log(0.025)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days := testDays(len(test.cases))
+			estimates, err := EstimateRt(&days, &test.cases, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*estimates) != 1 {
+				t.Fatalf("got %d estimates, want 1", len(*estimates))
+			}
+
+			e := (*estimates)[0]
+			if math.Abs(e.Rt-test.rt) > 1e-9 {
+				t.Errorf("Rt %v, want %v", e.Rt, test.rt)
+			}
+			if math.Abs(e.Inferiore-test.lower) > 1e-6 || math.Abs(e.Superiore-test.upper) > 1e-6 {
+				t.Errorf("credible interval [%v, %v], want [%v, %v]", e.Inferiore, e.Superiore, test.lower, test.upper)
+			}
+		})
+	}
+}
+
+func TestEstimateRtErrors(t *testing.T) {
+	config := DefaultRtConfig()
+	tests := []struct {
+		name   string
+		days   int
+		cases  []float64
+		config RtConfig
+	}{
+		{"different lengths", 3, []float64{1, 2}, config},
+		{"shorter than the window", 7, []float64{1, 2, 3, 4, 5, 6, 7}, config},
+		{"no cases", 30, make([]float64, 30), config},
+		{"wrong window", 3, []float64{1, 2, 3}, RtConfig{SerialIntervalMean: 6.6, SerialIntervalSd: 4.88, PriorMean: 5, PriorSd: 5, CredibleInterval: 0.95}},
+		{"wrong serial interval", 10, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, RtConfig{Window: 7, PriorMean: 5, PriorSd: 5, CredibleInterval: 0.95}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days := testDays(test.days)
+			if _, err := EstimateRt(&days, &test.cases, test.config); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"time"
+)
+
+// Returns dates and values of the specified national data field
+func NationSeries(data *[]NationData, fieldName string) (*[]time.Time, *[]float64, error) {
+	dates, values, _, err := nationToTimeseries(data, fieldName, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dates, values, nil
+}
+
+// Returns dates and values of the specified regional data field for the given region
+func RegionSeries(data *[]RegionData, fieldName string, regionName string) (*[]time.Time, *[]float64, error) {
+	regionIndex, err := FindFirstOccurrenceRegion(data, "denominazione_regione", regionName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("region %v: %v", regionName, err)
+	}
+
+	dates, values, _, err := regionToTimeseries(data, fieldName, regionIndex, regionIndex%21)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dates, values, nil
+}
+
+// Returns dates and values of the specified provincial data field for the given province
+func ProvinceSeries(data *[]ProvinceData, fieldName string, provinceName string) (*[]time.Time, *[]float64, error) {
+	provinceIndexes := GetProvinceIndexesByName(data, provinceName)
+	if len(*provinceIndexes) == 0 {
+		return nil, nil, fmt.Errorf("province %v: element not found", provinceName)
+	}
+
+	dates, values, _, err := provinceToTimeseries(data, fieldName, provinceIndexes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dates, values, nil
+}
+
+// Returns dates and values per 100.000 inhabitants of the specified national data field and the population year used
+func NationSeriesPer100k(data *[]NationData, fieldName string, year int) (*[]time.Time, *[]float64, int, error) {
+	dates, values, err := NationSeries(data, fieldName)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	population, populationYear, err := GetNationPopulation(year)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return dates, per100k(values, population), populationYear, nil
+}
+
+// Returns dates and values per 100.000 inhabitants of the specified regional data field and the population year used
+func RegionSeriesPer100k(data *[]RegionData, fieldName string, regionName string, year int) (*[]time.Time, *[]float64, int, error) {
+	dates, values, err := RegionSeries(data, fieldName, regionName)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	population, populationYear, err := GetRegionPopulation(regionName, year)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("region %v: %v", regionName, err)
+	}
+
+	return dates, per100k(values, population), populationYear, nil
+}
+
+// Returns dates and values per 100.000 inhabitants of the specified provincial data field and the population year used
+func ProvinceSeriesPer100k(data *[]ProvinceData, fieldName string, provinceName string, year int) (*[]time.Time, *[]float64, int, error) {
+	dates, values, err := ProvinceSeries(data, fieldName, provinceName)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	population, populationYear, err := GetProvincePopulation(provinceName, year)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("province %v: %v", provinceName, err)
+	}
+
+	return dates, per100k(values, population), populationYear, nil
+}
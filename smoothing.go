@@ -0,0 +1,174 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Method used to smooth a series
+type SmoothingMethod int
+
+const (
+	SmoothingSMA SmoothingMethod = iota
+	SmoothingEMA
+	SmoothingLoess
+)
+
+// Smoothing transform: Window and Centered are used by the simple moving average,
+// Alpha by the exponential one and Span, the fraction of points of each local fit, by LOESS
+type Smoothing struct {
+	Method   SmoothingMethod
+	Window   int
+	Centered bool
+	Alpha    float64
+	Span     float64
+}
+
+// Returns the description of the smoothing shown on plots
+func (s Smoothing) String() string {
+	return s.describe(PeriodDay)
+}
+
+// Returns the description of the smoothing of values aggregated by the given period
+func (s Smoothing) describe(period Period) string {
+	units := map[Period]string{PeriodDay: "giorni", PeriodWeek: "settimane", PeriodMonth: "mesi"}
+
+	switch s.Method {
+	case SmoothingSMA:
+		if s.Centered {
+			return fmt.Sprintf("media mobile centrata a %d %v", s.Window, units[period])
+		}
+		return fmt.Sprintf("media mobile a %d %v", s.Window, units[period])
+	case SmoothingEMA:
+		return fmt.Sprintf("media mobile esponenziale α=%g", s.Alpha)
+	case SmoothingLoess:
+		return fmt.Sprintf("LOESS span %g", s.Span)
+	default:
+		return "smussamento"
+	}
+}
+
+// Applies the smoothing to the values
+func (s Smoothing) Apply(values *[]float64) (*[]float64, error) {
+	switch s.Method {
+	case SmoothingSMA:
+		return SimpleMovingAverage(values, s.Window, s.Centered)
+	case SmoothingEMA:
+		return ExponentialMovingAverage(values, s.Alpha)
+	case SmoothingLoess:
+		return Loess(values, s.Span)
+	default:
+		return nil, fmt.Errorf("wrong smoothing method passed")
+	}
+}
+
+// Calculates the simple moving average over the given window, trailing or centered on each point.
+// Near the edges the average is taken over the available points only
+func SimpleMovingAverage(values *[]float64, window int, centered bool) (*[]float64, error) {
+	if window < 1 {
+		return nil, fmt.Errorf("wrong window passed")
+	}
+
+	before := window - 1
+	if centered {
+		before = window / 2
+	}
+	after := window - 1 - before
+
+	averages := make([]float64, len(*values))
+	for i := range *values {
+		start := i - before
+		if start < 0 {
+			start = 0
+		}
+		end := i + after
+		if end > len(*values)-1 {
+			end = len(*values) - 1
+		}
+
+		sum := 0.0
+		for j := start; j <= end; j++ {
+			sum += (*values)[j]
+		}
+		averages[i] = sum / float64(end-start+1)
+	}
+
+	return &averages, nil
+}
+
+// Calculates the exponential moving average with the given smoothing factor, starting from the first value
+func ExponentialMovingAverage(values *[]float64, alpha float64) (*[]float64, error) {
+	if alpha <= 0 || alpha > 1 {
+		return nil, fmt.Errorf("wrong alpha passed")
+	}
+
+	averages := make([]float64, len(*values))
+	for i, v := range *values {
+		if i == 0 {
+			averages[i] = v
+		} else {
+			averages[i] = alpha*v + (1-alpha)*averages[i-1]
+		}
+	}
+
+	return &averages, nil
+}
+
+// Calculates the LOESS smoothing with local linear fits weighted by the tricube function,
+// each fit using the given fraction of the points nearest to the smoothed one
+func Loess(values *[]float64, span float64) (*[]float64, error) {
+	if span <= 0 || span > 1 {
+		return nil, fmt.Errorf("wrong span passed")
+	}
+
+	n := len(*values)
+	neighbours := int(math.Ceil(span * float64(n)))
+	if neighbours < 3 {
+		neighbours = 3
+	}
+	if neighbours > n {
+		neighbours = n
+	}
+
+	smoothed := make([]float64, n)
+	distances := make([]float64, n)
+	for i := range *values {
+		for j := range distances {
+			distances[j] = math.Abs(float64(j - i))
+		}
+		sorted := append([]float64(nil), distances...)
+		sort.Float64s(sorted)
+		maxDistance := sorted[neighbours-1]
+		if maxDistance == 0 {
+			smoothed[i] = (*values)[i]
+			continue
+		}
+
+		var sw, swx, swy, swxx, swxy float64
+		for j, y := range *values {
+			u := distances[j] / (maxDistance * 1.000001)
+			if u >= 1 {
+				continue
+			}
+			w := math.Pow(1-u*u*u, 3)
+			x := float64(j)
+			sw += w
+			swx += w * x
+			swy += w * y
+			swxx += w * x * x
+			swxy += w * x * y
+		}
+
+		denominator := sw*swxx - swx*swx
+		if denominator == 0 {
+			smoothed[i] = swy / sw
+			continue
+		}
+		slope := (sw*swxy - swx*swy) / denominator
+		intercept := (swy - slope*swx) / sw
+		smoothed[i] = intercept + slope*float64(i)
+	}
+
+	return &smoothed, nil
+}
@@ -0,0 +1,106 @@
+package covidgraphs
+
+import (
+	"time"
+
+	"github.com/wcharczuk/go-chart"
+	"github.com/wcharczuk/go-chart/drawing"
+)
+
+// Hours the automatic theme switches to the light and to the dark theme at
+const (
+	daySwitch   = 6
+	nightSwitch = 19
+)
+
+// Colors of a plot. Name ends up in the file names of the plots saved without one.
+// Palette colors the lines of the plots comparing areas, in order
+type Theme struct {
+	Name       string
+	Background drawing.Color
+	Font       drawing.Color
+	Grid       drawing.Color
+	Palette    []drawing.Color
+}
+
+// Automatic choice between the light and the dark theme by the time of the day in the given location,
+// dark from 19 to 6. The location is UTC and the clock time.Now when not set
+type AutoTheme struct {
+	Location *time.Location
+	Clock    func() time.Time
+}
+
+// Returns the theme with dark text on a white background
+func LightTheme() Theme {
+	return Theme{
+		Name:       "light",
+		Background: chart.ColorWhite,
+		Font:       chart.ColorBlack,
+		Grid:       chart.ColorAlternateGray,
+		Palette:    append([]drawing.Color{}, comparisonColors...),
+	}
+}
+
+// Returns the theme with white text on a black background
+func DarkTheme() Theme {
+	return Theme{
+		Name:       "dark",
+		Background: chart.ColorBlack,
+		Font:       chart.ColorWhite,
+		Grid:       chart.ColorAlternateGray,
+		Palette:    append([]drawing.Color{}, comparisonColors...),
+	}
+}
+
+// Returns the theme with black text and grid on a white background and a palette told apart by color blind people too
+func HighContrastTheme() Theme {
+	return Theme{
+		Name:       "high-contrast",
+		Background: chart.ColorWhite,
+		Font:       chart.ColorBlack,
+		Grid:       drawing.Color{R: 90, G: 90, B: 90, A: 255},
+		Palette: []drawing.Color{
+			{R: 0, G: 114, B: 178, A: 255},
+			{R: 213, G: 94, B: 0, A: 255},
+			{R: 0, G: 158, B: 115, A: 255},
+			{R: 204, G: 121, B: 167, A: 255},
+			{R: 0, G: 0, B: 0, A: 255},
+			{R: 230, G: 159, B: 0, A: 255},
+			{R: 86, G: 180, B: 233, A: 255},
+		},
+	}
+}
+
+// Returns the theme for the current time of the automatic mode
+func (a AutoTheme) Theme() Theme {
+	now := time.Now()
+	if a.Clock != nil {
+		now = a.Clock()
+	}
+	location := a.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	hour := now.In(location).Hour()
+	if hour >= nightSwitch || hour < daySwitch {
+		return DarkTheme()
+	}
+	return LightTheme()
+}
+
+// Returns the theme of the options: the given one, the automatic one or the light theme when none is chosen.
+// Themes without a palette use the one of the light theme
+func (o ChartOptions) theme() Theme {
+	theme := LightTheme()
+	if o.Theme != nil {
+		theme = *o.Theme
+	} else if o.AutoTheme != nil {
+		theme = o.AutoTheme.Theme()
+	}
+	if len(theme.Palette) == 0 {
+		theme.Palette = LightTheme().Palette
+	}
+
+	return theme
+}
@@ -0,0 +1,206 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Epidemic wave found in a daily series. The peak is the highest smoothed value and the wave lasts
+// while the smoothed values stay above the threshold of the peak. Totale sums the daily amounts of the wave,
+// it is NaN for stocks and rates. InCorso is true when the wave has not ended by the last day
+type Wave struct {
+	Numero      int
+	Inizio      time.Time
+	Picco       time.Time
+	ValorePicco float64
+	Fine        time.Time
+	Totale      float64
+	InCorso     bool
+}
+
+// Parameters of the wave detection.
+// Peaks whose prominence, the height above the higher of the lowest points separating them from higher peaks,
+// is below MinProminence times the highest value are not waves of their own.
+// Threshold is the fraction of the peak marking the start and the end of a wave
+type WaveConfig struct {
+	Smoothing     Smoothing
+	MinProminence float64
+	Threshold     float64
+}
+
+// Returns the configuration used by default: a centered two weeks average, peaks rising at least
+// a fifth of the highest value above the troughs and waves lasting while above a tenth of their peak
+func DefaultWaveConfig() WaveConfig {
+	return WaveConfig{
+		Smoothing:     Smoothing{Method: SmoothingSMA, Window: 14, Centered: true},
+		MinProminence: 0.2,
+		Threshold:     0.1,
+	}
+}
+
+// Finds the waves of a series of the given kind, sorted by date.
+// Cumulative fields are checked on their daily amounts, the other ones on their values
+func DetectWaves(dates *[]time.Time, values *[]float64, kind FieldKind, config WaveConfig) (*[]Wave, error) {
+	if len(*dates) != len(*values) {
+		return nil, fmt.Errorf("dates and values have different lengths")
+	}
+	if config.MinProminence <= 0 || config.MinProminence > 1 || config.Threshold <= 0 || config.Threshold >= 1 {
+		return nil, fmt.Errorf("wrong wave configuration passed")
+	}
+
+	waves := make([]Wave, 0)
+	if len(*values) < 3 {
+		return &waves, nil
+	}
+
+	daily := *values
+	if kind == FieldCumulative {
+		daily = make([]float64, len(*values))
+		for i, v := range *values {
+			if i > 0 {
+				daily[i] = v - (*values)[i-1]
+			} else {
+				daily[i] = v
+			}
+		}
+	}
+	smoothed, err := config.Smoothing.Apply(&daily)
+	if err != nil {
+		return nil, err
+	}
+
+	peaks := wavePeaks(*smoothed, config.MinProminence)
+	last := len(*smoothed) - 1
+	for k, peak := range peaks {
+		// waves are separated by the lowest point between their peaks
+		first, end := 0, last
+		if k > 0 {
+			first = argMin(*smoothed, peaks[k-1], peak)
+		}
+		if k < len(peaks)-1 {
+			end = argMin(*smoothed, peak, peaks[k+1])
+		}
+
+		threshold := config.Threshold * (*smoothed)[peak]
+		start := peak
+		for start > first && (*smoothed)[start-1] >= threshold {
+			start--
+		}
+		stop := peak
+		for stop < end && (*smoothed)[stop+1] >= threshold {
+			stop++
+		}
+
+		total := math.NaN()
+		if kind == FieldFlow || kind == FieldCumulative {
+			total = 0
+			for _, v := range daily[start : stop+1] {
+				total += v
+			}
+		}
+
+		waves = append(waves, Wave{
+			Numero:      k + 1,
+			Inizio:      (*dates)[start],
+			Picco:       (*dates)[peak],
+			ValorePicco: (*smoothed)[peak],
+			Fine:        (*dates)[stop],
+			Totale:      total,
+			InCorso:     stop == last,
+		})
+	}
+
+	return &waves, nil
+}
+
+// Returns the indexes of the peaks with enough prominence, in order
+func wavePeaks(values []float64, minProminence float64) []int {
+	highest := math.Inf(-1)
+	for _, v := range values {
+		highest = math.Max(highest, v)
+	}
+	if highest <= 0 {
+		return []int{}
+	}
+
+	peaks := make([]int, 0)
+	last := len(values) - 1
+	for i, v := range values {
+		// plateaus count once, on their first day
+		if (i > 0 && v <= values[i-1]) || (i < last && v < values[i+1]) {
+			continue
+		}
+		if i == 0 && v == values[1] {
+			continue
+		}
+
+		leftMin := v
+		for j := i - 1; j >= 0 && values[j] <= v; j-- {
+			leftMin = math.Min(leftMin, values[j])
+		}
+		rightMin := v
+		for j := i + 1; j <= last && values[j] <= v; j++ {
+			rightMin = math.Min(rightMin, values[j])
+		}
+		if v-math.Max(leftMin, rightMin) >= minProminence*highest {
+			peaks = append(peaks, i)
+		}
+	}
+
+	return peaks
+}
+
+// Returns the index of the lowest value between two indexes
+func argMin(values []float64, from, to int) int {
+	lowest := from
+	for i := from; i <= to; i++ {
+		if values[i] < values[lowest] {
+			lowest = i
+		}
+	}
+
+	return lowest
+}
+
+// Finds the waves of the given national field
+func NationWaves(data *[]NationData, fieldName string, config WaveConfig) (*[]Wave, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := NationSeries(data, fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	return DetectWaves(dates, values, kind, config)
+}
+
+// Finds the waves of the given regional field for the given region
+func RegionWaves(data *[]RegionData, fieldName string, regionName string, config WaveConfig) (*[]Wave, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := RegionSeries(data, fieldName, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return DetectWaves(dates, values, kind, config)
+}
+
+// Finds the waves of the given provincial field for the given province
+func ProvinceWaves(data *[]ProvinceData, fieldName string, provinceName string, config WaveConfig) (*[]Wave, error) {
+	kind, err := GetFieldKind(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	dates, values, err := ProvinceSeries(data, fieldName, provinceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return DetectWaves(dates, values, kind, config)
+}
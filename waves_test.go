@@ -0,0 +1,124 @@
+package covidgraphs
+
+import (
+	"math"
+	"testing"
+)
+
+// Returns a triangular wave rising by step a day from the start to the peak and falling back as fast
+func triangle(i, start, peak int, step float64) float64 {
+	distance := math.Abs(float64(i - peak))
+	if i <= start || distance >= float64(peak-start) {
+		return 0
+	}
+	return step * (float64(peak-start) - distance)
+}
+
+// Returns 60 days with a wave of peak 100 on day 15 and one of peak 200 on day 40
+func twoWaves(i int) float64 {
+	return triangle(i, 5, 15, 10) + triangle(i, 30, 40, 20)
+}
+
+func TestDetectWaves(t *testing.T) {
+	// without smoothing the waves are found on the values themselves
+	config := WaveConfig{Smoothing: Smoothing{Method: SmoothingSMA, Window: 1}, MinProminence: 0.2, Threshold: 0.1}
+	first := Wave{Numero: 1, Inizio: testDay(6), Picco: testDay(15), ValorePicco: 100, Fine: testDay(24), Totale: 1000}
+	second := Wave{Numero: 2, Inizio: testDay(31), Picco: testDay(40), ValorePicco: 200, Fine: testDay(49), Totale: 2000}
+
+	tests := []struct {
+		name   string
+		days   int
+		kind   FieldKind
+		values func(i int) float64
+		want   []Wave
+	}{
+		{"daily amounts", 60, FieldFlow, twoWaves, []Wave{first, second}},
+		{"cumulative values", 60, FieldCumulative, func(i int) float64 {
+			total := 0.0
+			for day := 0; day <= i; day++ {
+				total += twoWaves(day)
+			}
+			return total
+		}, []Wave{first, second}},
+		{"stock", 60, FieldStock, twoWaves, []Wave{
+			{Numero: 1, Inizio: testDay(6), Picco: testDay(15), ValorePicco: 100, Fine: testDay(24), Totale: math.NaN()},
+			{Numero: 2, Inizio: testDay(31), Picco: testDay(40), ValorePicco: 200, Fine: testDay(49), Totale: math.NaN()},
+		}},
+		// a bump lower than a fifth of the highest peak is part of the quiet period
+		{"low bump", 60, FieldFlow, func(i int) float64 { return twoWaves(i) + triangle(i, 52, 55, 10) }, []Wave{first, second}},
+		{"wave still going on", 45, FieldFlow, twoWaves, []Wave{first,
+			{Numero: 2, Inizio: testDay(31), Picco: testDay(40), ValorePicco: 200, Fine: testDay(44), Totale: 1700, InCorso: true},
+		}},
+		// a peak lasting three days is a single one, on its first day
+		{"plateau", 60, FieldFlow, func(i int) float64 {
+			if i >= 15 && i <= 17 {
+				return 100
+			}
+			if i > 17 {
+				return twoWaves(i - 2)
+			}
+			return twoWaves(i)
+		}, []Wave{
+			{Numero: 1, Inizio: testDay(6), Picco: testDay(15), ValorePicco: 100, Fine: testDay(26), Totale: 1200},
+			{Numero: 2, Inizio: testDay(33), Picco: testDay(42), ValorePicco: 200, Fine: testDay(51), Totale: 2000},
+		}},
+		{"nothing", 60, FieldFlow, func(i int) float64 { return 0 }, []Wave{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days := testDays(test.days)
+			values := make([]float64, test.days)
+			for i := range values {
+				values[i] = test.values(i)
+			}
+			waves, err := DetectWaves(&days, &values, test.kind, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(*waves) != len(test.want) {
+				t.Fatalf("got %+v, want %+v", *waves, test.want)
+			}
+			for i, w := range *waves {
+				want := test.want[i]
+				if w.Numero != want.Numero || !w.Inizio.Equal(want.Inizio) || !w.Picco.Equal(want.Picco) || !w.Fine.Equal(want.Fine) ||
+					!sameValue(w.ValorePicco, want.ValorePicco) || !sameValue(w.Totale, want.Totale) || w.InCorso != want.InCorso {
+					t.Errorf("got %+v, want %+v", w, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectWavesSmoothed(t *testing.T) {
+	// daily noise around the two waves does not make waves of its own once smoothed
+	days := testDays(60)
+	values := make([]float64, len(days))
+	for i := range values {
+		values[i] = twoWaves(i) + float64((i*7)%5)*3
+	}
+
+	waves, err := DetectWaves(&days, &values, FieldFlow, DefaultWaveConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*waves) != 2 {
+		t.Fatalf("got %+v, want two waves", *waves)
+	}
+	for i, peak := range []int{15, 40} {
+		if distance := (*waves)[i].Picco.Sub(testDay(peak)).Hours() / 24; math.Abs(distance) > 2 {
+			t.Errorf("wave %d peaks on %v, want about %v", i+1, (*waves)[i].Picco, testDay(peak))
+		}
+	}
+}
+
+func TestDetectWavesErrors(t *testing.T) {
+	days := testDays(3)
+	if _, err := DetectWaves(&days, &[]float64{1, 2}, FieldFlow, DefaultWaveConfig()); err == nil {
+		t.Error("expected an error for different lengths")
+	}
+	if _, err := DetectWaves(&days, &[]float64{1, 2, 3}, FieldFlow, WaveConfig{Smoothing: Smoothing{Window: 1}}); err == nil {
+		t.Error("expected an error for a wrong configuration")
+	}
+}
@@ -0,0 +1,417 @@
+package covidgraphs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// Lossless WebP encoding of the plots. The pixels are written without transforms nor color cache,
+// repeating the pixel on the left or the one above through backward references, which is enough for
+// the flat colors of a chart
+
+// Sizes of the alphabets of the green, red, blue, alpha and distance prefix codes
+var webpAlphabetSizes = [5]int{256 + 24, 256, 256, 256, 40}
+
+// Order the lengths of the code length code are written in
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+const (
+	// Longest backward reference
+	webpMaxCopy = 4096
+	// Shortest backward reference worth writing instead of the literal pixels
+	webpMinCopy = 3
+	// Distance codes of the pixel above and of the one on the left
+	webpDistanceAbove = 1
+	webpDistanceLeft  = 2
+)
+
+// Pixel of the image, either a literal color or a backward reference to the pixels before it
+type webpToken struct {
+	argb     uint32
+	length   int
+	distance int
+}
+
+// Writes bits to a byte buffer, least significant bit first
+type webpBitWriter struct {
+	buffer []byte
+	bits   uint64
+	nBits  uint
+}
+
+func (b *webpBitWriter) write(value uint32, n uint) {
+	b.bits |= uint64(value) << b.nBits
+	b.nBits += n
+	for b.nBits >= 8 {
+		b.buffer = append(b.buffer, byte(b.bits))
+		b.bits >>= 8
+		b.nBits -= 8
+	}
+}
+
+func (b *webpBitWriter) flush() []byte {
+	if b.nBits > 0 {
+		b.buffer = append(b.buffer, byte(b.bits))
+		b.bits, b.nBits = 0, 0
+	}
+	return b.buffer
+}
+
+// Canonical prefix code of an alphabet, a code with a single symbol takes no bits
+type webpPrefixCode struct {
+	lengths []int
+	codes   []uint32
+	single  bool
+}
+
+func (c *webpPrefixCode) write(b *webpBitWriter, symbol int) {
+	if c.single {
+		return
+	}
+	b.write(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// Encodes an image as a lossless WebP
+func encodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return fmt.Errorf("error while encoding webp: wrong image size %dx%d", width, height)
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	pixels := make([]uint32, width*height)
+	opaque := true
+	for i := range pixels {
+		p := nrgba.Pix[4*i : 4*i+4]
+		pixels[i] = uint32(p[3])<<24 | uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+		opaque = opaque && p[3] == 255
+	}
+
+	tokens := webpTokens(pixels, width)
+	var histograms [5][]int
+	for i, size := range webpAlphabetSizes {
+		histograms[i] = make([]int, size)
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			histograms[0][t.argb>>8&0xff]++
+			histograms[1][t.argb>>16&0xff]++
+			histograms[2][t.argb&0xff]++
+			histograms[3][t.argb>>24]++
+			continue
+		}
+		symbol, _, _ := webpPrefix(t.length)
+		histograms[0][256+symbol]++
+		symbol, _, _ = webpPrefix(t.distance)
+		histograms[4][symbol]++
+	}
+
+	b := &webpBitWriter{}
+	b.write(0x2f, 8)
+	b.write(uint32(width-1), 14)
+	b.write(uint32(height-1), 14)
+	if opaque {
+		b.write(0, 1)
+	} else {
+		b.write(1, 1)
+	}
+	b.write(0, 3)
+	// no transforms, no color cache and a single group of prefix codes
+	b.write(0, 1)
+	b.write(0, 1)
+	b.write(0, 1)
+
+	var codes [5]*webpPrefixCode
+	for i, histogram := range histograms {
+		codes[i] = writeWebpPrefixCode(b, histogram)
+	}
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[0].write(b, int(t.argb>>8&0xff))
+			codes[1].write(b, int(t.argb>>16&0xff))
+			codes[2].write(b, int(t.argb&0xff))
+			codes[3].write(b, int(t.argb>>24))
+			continue
+		}
+		symbol, extraBits, extra := webpPrefix(t.length)
+		codes[0].write(b, 256+symbol)
+		b.write(extra, extraBits)
+		symbol, extraBits, extra = webpPrefix(t.distance)
+		codes[4].write(b, symbol)
+		b.write(extra, extraBits)
+	}
+	data := b.flush()
+
+	header := make([]byte, 20)
+	size := len(data) + len(data)%2
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(12+size))
+	copy(header[8:16], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(data)))
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// Splits the pixels into literals and copies of the runs matching the pixel on the left or the row above
+func webpTokens(pixels []uint32, width int) []webpToken {
+	tokens := make([]webpToken, 0)
+	for p := 0; p < len(pixels); {
+		left, above := 0, 0
+		if p >= 1 {
+			for left < webpMaxCopy && p+left < len(pixels) && pixels[p+left] == pixels[p+left-1] {
+				left++
+			}
+		}
+		if p >= width {
+			for above < webpMaxCopy && p+above < len(pixels) && pixels[p+above] == pixels[p+above-width] {
+				above++
+			}
+		}
+
+		switch {
+		case left >= webpMinCopy && left >= above:
+			tokens = append(tokens, webpToken{length: left, distance: webpDistanceLeft})
+			p += left
+		case above >= webpMinCopy:
+			tokens = append(tokens, webpToken{length: above, distance: webpDistanceAbove})
+			p += above
+		default:
+			tokens = append(tokens, webpToken{argb: pixels[p]})
+			p++
+		}
+	}
+
+	return tokens
+}
+
+// Returns the prefix symbol of a backward reference length or distance code, with its extra bits
+func webpPrefix(value int) (int, uint, uint32) {
+	v := value - 1
+	if v < 4 {
+		return v, 0, 0
+	}
+	highest := 0
+	for v>>(highest+1) > 0 {
+		highest++
+	}
+	second := (v >> (highest - 1)) & 1
+	extraBits := uint(highest - 1)
+
+	return 2*highest + second, extraBits, uint32(v) & (1<<extraBits - 1)
+}
+
+// Writes the prefix code of a histogram and returns it
+func writeWebpPrefixCode(b *webpBitWriter, histogram []int) *webpPrefixCode {
+	symbols := make([]int, 0)
+	for symbol, count := range histogram {
+		if count > 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	// simple codes hold one or two symbols below 256
+	if len(symbols) <= 2 && (len(symbols) == 0 || symbols[len(symbols)-1] < 256) {
+		if len(symbols) == 0 {
+			symbols = append(symbols, 0)
+		}
+		b.write(1, 1)
+		b.write(uint32(len(symbols)-1), 1)
+		if symbols[0] < 2 {
+			b.write(0, 1)
+			b.write(uint32(symbols[0]), 1)
+		} else {
+			b.write(1, 1)
+			b.write(uint32(symbols[0]), 8)
+		}
+		code := &webpPrefixCode{lengths: make([]int, len(histogram)), codes: make([]uint32, len(histogram)), single: len(symbols) == 1}
+		if len(symbols) == 2 {
+			b.write(uint32(symbols[1]), 8)
+			code.lengths[symbols[0]], code.lengths[symbols[1]] = 1, 1
+			code.codes[symbols[1]] = 1
+		}
+		return code
+	}
+
+	code := newWebpPrefixCode(histogram, 15)
+
+	// the code lengths are written with the code length code, running zeros are packed
+	lengthSymbols := make([]int, 0)
+	lengthExtras := make([]uint32, 0)
+	for i := 0; i < len(code.lengths); {
+		if code.lengths[i] != 0 {
+			lengthSymbols = append(lengthSymbols, code.lengths[i])
+			lengthExtras = append(lengthExtras, 0)
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(code.lengths) && code.lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run >= 11:
+			lengthSymbols = append(lengthSymbols, 18)
+			lengthExtras = append(lengthExtras, uint32(run-11))
+		case run >= 3:
+			lengthSymbols = append(lengthSymbols, 17)
+			lengthExtras = append(lengthExtras, uint32(run-3))
+		default:
+			run = 1
+			lengthSymbols = append(lengthSymbols, 0)
+			lengthExtras = append(lengthExtras, 0)
+		}
+		i += run
+	}
+
+	lengthHistogram := make([]int, 19)
+	for _, symbol := range lengthSymbols {
+		lengthHistogram[symbol]++
+	}
+	lengthCode := newWebpPrefixCode(lengthHistogram, 7)
+	written := 4
+	for i, symbol := range webpCodeLengthOrder {
+		if lengthCode.lengths[symbol] > 0 && i+1 > written {
+			written = i + 1
+		}
+	}
+
+	b.write(0, 1)
+	b.write(uint32(written-4), 4)
+	for _, symbol := range webpCodeLengthOrder[:written] {
+		b.write(uint32(lengthCode.lengths[symbol]), 3)
+	}
+	// every symbol of the alphabet is written
+	b.write(0, 1)
+	for i, symbol := range lengthSymbols {
+		lengthCode.write(b, symbol)
+		switch symbol {
+		case 17:
+			b.write(lengthExtras[i], 3)
+		case 18:
+			b.write(lengthExtras[i], 7)
+		}
+	}
+
+	return code
+}
+
+// Builds the canonical prefix code of a histogram with codes no longer than maxLength bits.
+// The codes are stored bit-reversed, as they are written least significant bit first
+func newWebpPrefixCode(histogram []int, maxLength int) *webpPrefixCode {
+	counts := append([]int{}, histogram...)
+	lengths := webpCodeLengths(counts)
+	for webpMaxLength(lengths) > maxLength {
+		// flattening the counts shortens the longest codes
+		for i, count := range counts {
+			if count > 0 {
+				counts[i] = (count + 1) / 2
+			}
+		}
+		lengths = webpCodeLengths(counts)
+	}
+
+	code := &webpPrefixCode{lengths: lengths, codes: make([]uint32, len(lengths))}
+	used := 0
+	lengthCounts := make([]int, maxLength+1)
+	for _, length := range lengths {
+		if length > 0 {
+			used++
+			lengthCounts[length]++
+		}
+	}
+	code.single = used == 1
+
+	next := make([]uint32, maxLength+1)
+	current := uint32(0)
+	for length := 1; length <= maxLength; length++ {
+		current = (current + uint32(lengthCounts[length-1])) << 1
+		next[length] = current
+	}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		value := next[length]
+		next[length]++
+		reversed := uint32(0)
+		for i := 0; i < length; i++ {
+			reversed = reversed<<1 | (value>>i)&1
+		}
+		code.codes[symbol] = reversed
+	}
+
+	return code
+}
+
+// Returns the Huffman code lengths of a histogram, a single used symbol gets a length of one
+func webpCodeLengths(counts []int) []int {
+	type node struct {
+		count  int
+		parent int
+	}
+	lengths := make([]int, len(counts))
+	nodes := make([]node, 0)
+	leaves := make([]int, 0)
+	for symbol, count := range counts {
+		if count > 0 {
+			leaves = append(leaves, symbol)
+			nodes = append(nodes, node{count: count, parent: -1})
+		}
+	}
+	if len(leaves) == 0 {
+		return lengths
+	}
+	if len(leaves) == 1 {
+		lengths[leaves[0]] = 1
+		return lengths
+	}
+
+	// merges the two lightest nodes until one is left
+	queue := make([]int, len(nodes))
+	for i := range queue {
+		queue[i] = i
+	}
+	for len(queue) > 1 {
+		sort.SliceStable(queue, func(i, j int) bool { return nodes[queue[i]].count < nodes[queue[j]].count })
+		parent := len(nodes)
+		nodes = append(nodes, node{count: nodes[queue[0]].count + nodes[queue[1]].count, parent: -1})
+		nodes[queue[0]].parent = parent
+		nodes[queue[1]].parent = parent
+		queue = append(queue[2:], parent)
+	}
+
+	for i, symbol := range leaves {
+		depth := 0
+		for n := i; nodes[n].parent >= 0; n = nodes[n].parent {
+			depth++
+		}
+		lengths[symbol] = depth
+	}
+
+	return lengths
+}
+
+// Returns the longest of the code lengths
+func webpMaxLength(lengths []int) int {
+	longest := 0
+	for _, length := range lengths {
+		if length > longest {
+			longest = length
+		}
+	}
+
+	return longest
+}
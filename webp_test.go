@@ -0,0 +1,117 @@
+package covidgraphs
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// Checks that the decoded image has the same pixels as the encoded one
+func checkWebPRoundTrip(t *testing.T, img image.Image) {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	if err := encodeWebP(&buffer, img); err != nil {
+		t.Fatalf("encoding: %v", err)
+	}
+	decoded, err := webp.Decode(&buffer)
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+
+	bounds := img.Bounds()
+	if decoded.Bounds().Dx() != bounds.Dx() || decoded.Bounds().Dy() != bounds.Dy() {
+		t.Fatalf("decoded size %v, want %v", decoded.Bounds().Size(), bounds.Size())
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			want := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			got := color.NRGBAModel.Convert(decoded.At(decoded.Bounds().Min.X+x, decoded.Bounds().Min.Y+y))
+			if want != got {
+				t.Fatalf("pixel %d,%d is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name  string
+		image func() image.Image
+	}{
+		{"single pixel", func() image.Image {
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.Set(0, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+			return img
+		}},
+		{"flat color", func() image.Image {
+			img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+			for i := range img.Pix {
+				img.Pix[i] = 200
+			}
+			return img
+		}},
+		{"stripes and transparency", func() image.Image {
+			img := image.NewNRGBA(image.Rect(0, 0, 50, 40))
+			for y := 0; y < 40; y++ {
+				for x := 0; x < 50; x++ {
+					img.Set(x, y, color.NRGBA{R: uint8(x / 10 * 50), G: uint8(y * 6), B: 90, A: uint8(255 - x%3*100)})
+				}
+			}
+			return img
+		}},
+		{"noise", func() image.Image {
+			img := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+			random.Read(img.Pix)
+			return img
+		}},
+		{"offset bounds", func() image.Image {
+			img := image.NewNRGBA(image.Rect(5, 7, 30, 20))
+			for i := range img.Pix {
+				img.Pix[i] = uint8(i % 7 * 30)
+			}
+			return img
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkWebPRoundTrip(t, test.image())
+		})
+	}
+}
+
+func TestEncodeWebPChart(t *testing.T) {
+	data := make([]NationData, 0)
+	for i := 0; i < 60; i++ {
+		data = append(data, NationData{
+			Data:           testDay(i).Format("2006-01-02T15:04:05"),
+			Nuovi_positivi: 100 + i*i%37*10,
+		})
+	}
+
+	png, err := PlotBytes(func(opts ChartOptions) (error, string) {
+		return VociNazione(&data, []string{"nuovi_positivi"}, 0, "Nuovi positivi", "", opts)
+	})
+	if err != nil {
+		t.Fatalf("plotting: %v", err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("decoding png: %v", err)
+	}
+
+	checkWebPRoundTrip(t, img)
+}
+
+func TestEncodeWebPWrongSize(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := encodeWebP(&buffer, image.NewNRGBA(image.Rect(0, 0, 0, 10))); err == nil {
+		t.Fatal("expected an error for an empty image")
+	}
+}
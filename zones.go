@@ -0,0 +1,243 @@
+package covidgraphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Zone assigned to a region according to its epidemiological indicators
+type Zone int
+
+const (
+	ZonaBianca Zone = iota
+	ZonaGialla
+	ZonaArancione
+	ZonaRossa
+)
+
+// Returns the zone name
+func (z Zone) String() string {
+	switch z {
+	case ZonaBianca:
+		return "bianca"
+	case ZonaGialla:
+		return "gialla"
+	case ZonaArancione:
+		return "arancione"
+	case ZonaRossa:
+		return "rossa"
+	default:
+		return "sconosciuta"
+	}
+}
+
+// Condition comparing a daily indicator to a threshold.
+// Available indicators are incidenza_7 and incidenza_14, the new cases per 100.000 inhabitants over 7 and 14 days,
+// and occupazione_terapia_intensiva and occupazione_area_medica, the percentages of occupied beds against the
+// bed capacity table, undefined for regions missing from it
+type ZoneCondition struct {
+	Indicator string
+	Operator  string
+	Threshold float64
+}
+
+// Rule assigning a zone when all its conditions hold for at least the given number of consecutive days
+type ZoneRule struct {
+	Zone            Zone
+	Conditions      []ZoneCondition
+	ConsecutiveDays int
+}
+
+// Rules evaluated in order, the first matching one assigns the zone and DefaultZone is used when none matches
+type ZoneRules struct {
+	Rules       []ZoneRule
+	DefaultZone Zone
+}
+
+// Zone assigned to a region on a day, along with the indicators it was assigned from
+type ZoneClassification struct {
+	Data                  time.Time
+	Denominazione_regione string
+	Zona                  Zone
+	Indicatori            map[string]float64
+}
+
+// Returns rules based on the weekly incidence thresholds of the 2021 decrees: zona rossa from 250 cases per 100.000
+// inhabitants and zona bianca below 50 for three weeks in a row. Since the official orange zone also depended on Rt and
+// on the risk assessment, here it is approximated with an incidence of 150
+func DefaultZoneRules() *ZoneRules {
+	return &ZoneRules{
+		Rules: []ZoneRule{
+			{
+				Zone:       ZonaRossa,
+				Conditions: []ZoneCondition{{Indicator: "incidenza_7", Operator: ">=", Threshold: 250}},
+			},
+			{
+				Zone:       ZonaArancione,
+				Conditions: []ZoneCondition{{Indicator: "incidenza_7", Operator: ">=", Threshold: 150}},
+			},
+			{
+				Zone:            ZonaBianca,
+				Conditions:      []ZoneCondition{{Indicator: "incidenza_7", Operator: "<", Threshold: 50}},
+				ConsecutiveDays: 21,
+			},
+		},
+		DefaultZone: ZonaGialla,
+	}
+}
+
+// Checks whether the condition holds for the given value, undefined values never satisfy a condition
+func (c ZoneCondition) holds(value float64) (bool, error) {
+	if math.IsNaN(value) {
+		return false, nil
+	}
+
+	switch c.Operator {
+	case "<":
+		return value < c.Threshold, nil
+	case "<=":
+		return value <= c.Threshold, nil
+	case ">":
+		return value > c.Threshold, nil
+	case ">=":
+		return value >= c.Threshold, nil
+	default:
+		return false, fmt.Errorf("wrong operator passed: %v", c.Operator)
+	}
+}
+
+// Assigns a zone to each of the days described by the indicators
+func (rules *ZoneRules) classify(indicators map[string][]float64, days int) ([]Zone, error) {
+	zones := make([]Zone, days)
+	assigned := make([]bool, days)
+
+	for _, rule := range rules.Rules {
+		streak := 0
+		for i := 0; i < days; i++ {
+			matching := true
+			for _, c := range rule.Conditions {
+				values, ok := indicators[c.Indicator]
+				if !ok {
+					return nil, fmt.Errorf("wrong indicator passed: %v", c.Indicator)
+				}
+				holds, err := c.holds(values[i])
+				if err != nil {
+					return nil, err
+				}
+				matching = matching && holds
+			}
+
+			if matching {
+				streak++
+			} else {
+				streak = 0
+			}
+			if !assigned[i] && matching && streak >= rule.ConsecutiveDays {
+				zones[i] = rule.Zone
+				assigned[i] = true
+			}
+		}
+	}
+
+	for i := range zones {
+		if !assigned[i] {
+			zones[i] = rules.DefaultZone
+		}
+	}
+
+	return zones, nil
+}
+
+// Computes the daily indicators zone rules can refer to for the given region, undefined values are NaN
+func regionIndicators(data *[]RegionData, regionName string, year int) (*[]time.Time, map[string][]float64, error) {
+	dates, cases, err := RegionSeries(data, "nuovi_positivi", regionName)
+	if err != nil {
+		return nil, nil, err
+	}
+	population, _, err := GetRegionPopulation(regionName, year)
+	if err != nil {
+		return nil, nil, fmt.Errorf("region %v: %v", regionName, err)
+	}
+
+	indicators := make(map[string][]float64)
+	for _, days := range []int{7, 14} {
+		_, values := incidence(dates, cases, population, days)
+		aligned := make([]float64, len(*dates))
+		for i := range aligned {
+			if i < days-1 {
+				aligned[i] = math.NaN()
+			} else {
+				aligned[i] = (*values)[i-days+1]
+			}
+		}
+		indicators[fmt.Sprintf("incidenza_%d", days)] = aligned
+	}
+
+	// regions missing from the bed capacity table have undefined occupancy
+	icu := make([]float64, len(*dates))
+	ward := make([]float64, len(*dates))
+	occupancies, err := RegionOccupancy(data, regionName)
+	for i := range *dates {
+		if err != nil {
+			icu[i], ward[i] = math.NaN(), math.NaN()
+		} else {
+			icu[i], ward[i] = (*occupancies)[i].Terapia_intensiva, (*occupancies)[i].Area_medica
+		}
+	}
+	indicators["occupazione_terapia_intensiva"] = icu
+	indicators["occupazione_area_medica"] = ward
+
+	return dates, indicators, nil
+}
+
+// Classifies the given region for each day according to the rules, DefaultZoneRules are used when rules is nil
+func ClassifyRegion(data *[]RegionData, regionName string, rules *ZoneRules, year int) (*[]ZoneClassification, error) {
+	if rules == nil {
+		rules = DefaultZoneRules()
+	}
+
+	dates, indicators, err := regionIndicators(data, regionName, year)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := rules.classify(indicators, len(*dates))
+	if err != nil {
+		return nil, err
+	}
+
+	classifications := make([]ZoneClassification, 0)
+	for i, date := range *dates {
+		dayIndicators := make(map[string]float64)
+		for name, values := range indicators {
+			dayIndicators[name] = values[i]
+		}
+		classifications = append(classifications, ZoneClassification{
+			Data:                  date,
+			Denominazione_regione: regionName,
+			Zona:                  zones[i],
+			Indicatori:            dayIndicators,
+		})
+	}
+
+	return &classifications, nil
+}
+
+// Classifies every region for each day according to the rules, DefaultZoneRules are used when rules is nil
+func ClassifyRegions(data *[]RegionData, rules *ZoneRules, year int) (*[]ZoneClassification, error) {
+	if len(*data) < 21 {
+		return nil, fmt.Errorf("not enough regional data")
+	}
+
+	firstDay := (*data)[:21]
+	classifications := make([]ZoneClassification, 0)
+	for _, regionName := range GetRegionsNamesList(&firstDay) {
+		regionClassifications, err := ClassifyRegion(data, regionName, rules, year)
+		if err != nil {
+			return nil, err
+		}
+		classifications = append(classifications, *regionClassifications...)
+	}
+
+	return &classifications, nil
+}